@@ -0,0 +1,140 @@
+/*
+ecnu-gen 根据 OpenAPI 3 或 Swagger 2 的 json 文档生成接口调用代码和模型
+
+	go run github.com/ecnu/ecnu-openapi-sdk-go/cmd/ecnu-gen -package orgapi -o orgapi/api.go spec.json
+
+没有接口文档时，model 子命令可以调用接口读取样例数据，推断字段类型后生成模型，
+接口的 client_id、client_secret 从 ECNU_ 前缀的环境变量或 -config 指定的配置文件读取：
+
+	go run github.com/ecnu/ecnu-openapi-sdk-go/cmd/ecnu-gen model -name FakeRowsWithTS -o model/fake.go /api/v1/sync/fakewithts
+
+不指定 -o 时输出到标准输出。
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk"
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk/codegen"
+)
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "model" {
+		modelMain(os.Args[2:])
+		return
+	}
+	output := flag.String("o", "", "输出文件，默认输出到标准输出")
+	pkg := flag.String("package", "", "生成代码的包名，默认为输出文件所在的目录名，没有时为 api")
+	keys := flag.String("key", "id", "作为主键的 json 字段名，多个用逗号分隔，文档中的 x-primary-key 优先")
+	timeFormat := flag.String("time", codegen.TimeFormatSQL, "date-time 字段的格式：sql_datetime 或 rfc3339")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: ecnu-gen [flags] spec.json\n       ecnu-gen model [flags] api_path\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0), *output, *pkg, *keys, *timeFormat); err != nil {
+		fmt.Fprintln(os.Stderr, "ecnu-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(specFile, output, pkg, keys, timeFormat string) error {
+	if timeFormat != codegen.TimeFormatSQL && timeFormat != codegen.TimeFormatRFC3339 {
+		return fmt.Errorf("not support time format: %s", timeFormat)
+	}
+	spec, err := os.ReadFile(specFile)
+	if err != nil {
+		return fmt.Errorf("read spec fail: %v", err)
+	}
+	opts := codegen.Options{Package: packageName(pkg, output), TimeFormat: timeFormat, Keys: splitKeys(keys)}
+	code, err := codegen.Generate(spec, opts)
+	if err != nil {
+		return err
+	}
+	return writeOutput(output, code)
+}
+
+func modelMain(args []string) {
+	fs := flag.NewFlagSet("ecnu-gen model", flag.ExitOnError)
+	output := fs.String("o", "", "输出文件，默认输出到标准输出")
+	pkg := fs.String("package", "", "生成代码的包名，默认为输出文件所在的目录名，没有时为 model")
+	name := fs.String("name", "", "结构体名称，默认根据接口路径的最后一段生成")
+	keys := fs.String("key", "id", "作为主键的 json 字段名，多个用逗号分隔")
+	size := fs.Int("size", codegen.DefaultSampleSize, "读取的样例行数")
+	config := fs.String("config", "", "配置文件（.json 或 .env），默认从 ECNU_ 前缀的环境变量读取")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: ecnu-gen model [flags] api_path\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	opts := codegen.ModelOptions{Package: packageName(*pkg, *output), Name: *name, Keys: splitKeys(*keys), SampleSize: *size}
+	if err := runModel(fs.Arg(0), *output, *config, opts); err != nil {
+		fmt.Fprintln(os.Stderr, "ecnu-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func runModel(apiPath, output, config string, opts codegen.ModelOptions) error {
+	var cf sdk.OAuth2Config
+	var err error
+	if config != "" {
+		cf, err = sdk.LoadConfig(config)
+	} else {
+		cf, err = sdk.ConfigFromEnv("ECNU")
+	}
+	if err != nil {
+		return err
+	}
+	if err := sdk.SetupOAuth2ClientCredentials(cf); err != nil {
+		return err
+	}
+	code, err := codegen.GenerateModel(sdk.GetOpenAPIClient(), apiPath, opts)
+	if err != nil {
+		return err
+	}
+	return writeOutput(output, code)
+}
+
+// packageName 没有指定包名时使用输出文件所在的目录名
+func packageName(pkg, output string) string {
+	if pkg == "" && output != "" {
+		if abs, err := filepath.Abs(output); err == nil {
+			pkg = filepath.Base(filepath.Dir(abs))
+		}
+	}
+	return pkg
+}
+
+func splitKeys(keys string) []string {
+	res := []string{}
+	for _, key := range strings.Split(keys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			res = append(res, key)
+		}
+	}
+	return res
+}
+
+func writeOutput(output string, code []byte) error {
+	if output == "" {
+		_, err := os.Stdout.Write(code)
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return fmt.Errorf("create output dir fail: %v", err)
+	}
+	return os.WriteFile(output, code, 0644)
+}
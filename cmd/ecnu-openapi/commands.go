@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk"
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk/codegen"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func runCall(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("call", "api_path", stderr)
+	var cf clientFlags
+	cf.register(fs)
+	method := fs.String("X", "", "请求方法，默认有请求体时为 POST，否则为 GET")
+	body := fs.String("d", "", "json 请求体，以 @ 开头时从文件读取")
+	var headers, params listFlag
+	fs.Var(&headers, "H", "请求头，格式为 key:value，可以重复指定")
+	fs.Var(&params, "p", "query 参数，格式为 key=value，可以重复指定")
+	apiPath, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	header, err := headers.split(":")
+	if err != nil {
+		return err
+	}
+	query, err := params.values()
+	if err != nil {
+		return err
+	}
+	content, err := readBody(*body)
+	if err != nil {
+		return err
+	}
+	c, err := cf.init()
+	if err != nil {
+		return err
+	}
+
+	req := c.R().Method(*method).Path(apiPath).Queries(query)
+	if content != nil {
+		req.JSON(json.RawMessage(content))
+	}
+	for _, h := range header {
+		req.Header(h[0], h[1])
+	}
+	resp, err := req.Do(context.Background())
+	if resp == nil || resp.StatusCode != 200 {
+		return err
+	}
+	result := sdk.APIResult{ErrCode: resp.ErrCode, ErrMsg: resp.ErrMsg, RequestId: resp.RequestId, Data: resp.Data}
+	output, merr := json.MarshalIndent(result, "", "  ")
+	if merr != nil {
+		return fmt.Errorf("format api result fail: %v", merr)
+	}
+	if _, werr := fmt.Fprintf(stdout, "%s\n", output); werr != nil {
+		return &codeError{code: exitOutput, err: werr}
+	}
+	return apiError(err)
+}
+
+// readBody 读取请求体，并校验是否为 json
+func readBody(body string) ([]byte, error) {
+	if body == "" {
+		return nil, nil
+	}
+	content := []byte(body)
+	if strings.HasPrefix(body, "@") {
+		var err error
+		if content, err = os.ReadFile(body[1:]); err != nil {
+			return nil, &codeError{code: exitUsage, err: fmt.Errorf("read body fail: %v", err)}
+		}
+	}
+	if !json.Valid(content) {
+		return nil, &codeError{code: exitUsage, err: errors.New("body is not valid json")}
+	}
+	return content, nil
+}
+
+// apiError errCode 不为 0 时，sdk 返回的错误只有 errMsg，输出时补充 errCode 和 requestId
+func apiError(err error) error {
+	var e *sdk.APIError
+	if errors.As(err, &e) {
+		return &codeError{code: exitAPI, err: fmt.Errorf("errCode: %d, errMsg: %s, requestId: %s", e.ErrCode, e.ErrMsg, e.RequestId)}
+	}
+	return err
+}
+
+// fetchPage 读取一页数据
+func fetchPage(ctx context.Context, c *sdk.OAuth2Client, apiPath string, params url.Values, pageNum, pageSize int, out interface{}) error {
+	_, err := c.R().
+		Path(apiPath).
+		Queries(params).
+		Query("pageNum", pageNum).
+		Query("pageSize", pageSize).
+		Into(out).
+		Do(ctx)
+	return apiError(err)
+}
+
+// pageFlags 分页读取数据的参数
+type pageFlags struct {
+	params   listFlag
+	pageSize int
+}
+
+func (pf *pageFlags) register(fs *flag.FlagSet, pageSize int) {
+	fs.Var(&pf.params, "p", "接口参数，格式为 key=value，可以重复指定")
+	fs.IntVar(&pf.pageSize, "page-size", pageSize, fmt.Sprintf("每页的行数，最大 %d", sdk.MAXPageSIZE))
+}
+
+// apiConfig 根据参数生成 APIConfig
+func (pf *pageFlags) apiConfig(apiPath string) (sdk.APIConfig, error) {
+	api := sdk.APIConfig{APIPath: apiPath, PageSize: pf.pageSize}
+	params, err := pf.params.values()
+	if err != nil {
+		return api, err
+	}
+	for key, values := range params {
+		for _, value := range values {
+			api.AddParam(key, value)
+		}
+	}
+	api.SetDefault()
+	return api, nil
+}
+
+func runRows(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("rows", "api_path", stderr)
+	var cf clientFlags
+	cf.register(fs)
+	var pf pageFlags
+	pf.register(fs, 2000)
+	limit := fs.Int("limit", 0, "最多输出的行数，0 表示全部")
+	apiPath, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	api, err := pf.apiConfig(apiPath)
+	if err != nil {
+		return err
+	}
+	c, err := cf.init()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	it := sdk.NewIterator(func(ctx context.Context, pageNum int) ([]json.RawMessage, error) {
+		var page sdk.Page[json.RawMessage]
+		err := fetchPage(ctx, c, api.APIPath, api.Params(), pageNum, api.PageSize, &page)
+		return page.Rows, err
+	})
+	w := bufio.NewWriter(stdout)
+	count := 0
+	for (*limit <= 0 || count < *limit) && it.Next(ctx) {
+		if _, err := fmt.Fprintf(w, "%s\n", compact(it.Value())); err != nil {
+			return &codeError{code: exitOutput, err: err}
+		}
+		count++
+	}
+	if err := w.Flush(); err != nil {
+		return &codeError{code: exitOutput, err: err}
+	}
+	return it.Err()
+}
+
+// compact 去掉 json 中的换行，保证每行一个对象
+func compact(row json.RawMessage) []byte {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, row); err != nil {
+		return row
+	}
+	return buf.Bytes()
+}
+
+func runExport(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("export", "api_path", stderr)
+	var cf clientFlags
+	cf.register(fs)
+	var pf pageFlags
+	pf.register(fs, 2000)
+	output := fs.String("o", "", "输出文件，必填")
+	format := fs.String("format", "", "文件格式：csv、xlsx 或 jsonl，默认根据输出文件的扩展名判断")
+	apiPath, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		return &codeError{code: exitUsage, err: errors.New("-o is required")}
+	}
+	mode := *format
+	if mode == "" {
+		mode = strings.TrimPrefix(filepath.Ext(*output), ".")
+	}
+	if mode != "csv" && mode != "xlsx" && mode != "jsonl" {
+		return &codeError{code: exitUsage, err: fmt.Errorf("not support format: %q, expected csv, xlsx or jsonl", mode)}
+	}
+	api, err := pf.apiConfig(apiPath)
+	if err != nil {
+		return err
+	}
+	c, err := cf.init()
+	if err != nil {
+		return err
+	}
+
+	// 先读取一行数据，接口不可用时按接口错误退出，而不是写文件失败
+	var page sdk.DataResult
+	if err := fetchPage(context.Background(), c, api.APIPath, api.Params(), 1, 1, &page); err != nil {
+		return err
+	}
+	res, err := c.SyncToFile(mode, *output, api)
+	if err != nil {
+		return withFallback(err, exitOutput)
+	}
+	fmt.Fprintf(stderr, "exported %d rows to %s\n", res.Rows, *output)
+	return nil
+}
+
+func runSync(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("sync", "api_path", stderr)
+	var cf clientFlags
+	cf.register(fs)
+	var pf pageFlags
+	pf.register(fs, 2000)
+	driver := fs.String("driver", "sqlite", "数据库类型：sqlite、mysql、postgres 或 sqlserver")
+	dsn := fs.String("dsn", "", "数据库连接串，sqlite 为文件路径，必填")
+	table := fs.String("table", "", "表名，默认为接口路径的最后一段")
+	keys := fs.String("key", "id", "作为主键的 json 字段名，多个用逗号分隔")
+	batchSize := fs.Int("batch-size", 100, "每批写入数据库的行数")
+	sample := fs.Int("sample", codegen.DefaultSampleSize, "推断表结构时读取的样例行数")
+	count := fs.Bool("count", false, "统计新增、更新和没有变化的行数，每批写入前多查询一次数据库")
+	migrate := fs.String("migrate", sdk.MigrateAuto, "表结构的处理方式：auto、create、verify 或 off")
+	apiPath, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *dsn == "" {
+		return &codeError{code: exitUsage, err: errors.New("-dsn is required")}
+	}
+	dialector, err := openDialector(*driver, *dsn)
+	if err != nil {
+		return err
+	}
+	api, err := pf.apiConfig(apiPath)
+	if err != nil {
+		return err
+	}
+	api.BatchSize = *batchSize
+	api.Migrate = *migrate
+	api.CountChanges = *count
+	if *table == "" {
+		*table = path.Base(strings.SplitN(apiPath, "?", 2)[0])
+	}
+	api.Table = *table
+	c, err := cf.init()
+	if err != nil {
+		return err
+	}
+
+	// 没有 Go 模型，根据样例数据推断表结构
+	var page sdk.DataResult
+	if err := fetchPage(context.Background(), c, api.APIPath, api.Params(), 1, *sample, &page); err != nil {
+		return err
+	}
+	s, err := codegen.InferStruct(codegen.GoName(*table), page.Rows, splitKeys(*keys))
+	if err != nil {
+		return err
+	}
+	typ, err := s.Type()
+	if err != nil {
+		return err
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return &codeError{code: exitOutput, err: fmt.Errorf("open database fail: %v", err)}
+	}
+	model := reflect.New(reflect.SliceOf(typ)).Interface()
+	res, err := c.SyncToDB(db, api, model)
+	if err != nil {
+		return withFallback(err, exitOutput)
+	}
+	fmt.Fprintf(stderr, "synced %d rows to %s: %d written, %d pages, %d bytes\n", res.Rows, *table, res.Written, res.Pages, res.Bytes)
+	if *count {
+		fmt.Fprintf(stderr, "%d inserted, %d updated, %d unchanged\n", res.Inserted, res.Updated, res.Unchanged)
+	}
+	return nil
+}
+
+// openDialector 根据数据库类型选择 go.mod 中已有的驱动
+func openDialector(driver, dsn string) (gorm.Dialector, error) {
+	switch driver {
+	case "sqlite":
+		return sqlite.Open(dsn), nil
+	case "mysql":
+		return mysql.Open(dsn), nil
+	case "postgres":
+		return postgres.Open(dsn), nil
+	case "sqlserver":
+		return sqlserver.Open(dsn), nil
+	}
+	return nil, &codeError{code: exitUsage, err: fmt.Errorf("not support driver: %s, expected sqlite, mysql, postgres or sqlserver", driver)}
+}
+
+func splitKeys(keys string) []string {
+	res := []string{}
+	for _, key := range strings.Split(keys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			res = append(res, key)
+		}
+	}
+	return res
+}
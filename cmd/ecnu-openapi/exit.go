@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk"
+	"golang.org/x/oauth2"
+)
+
+const (
+	exitOK      = 0
+	exitError   = 1
+	exitUsage   = 2
+	exitConfig  = 3
+	exitAuth    = 4
+	exitAPI     = 5
+	exitNetwork = 6
+	exitOutput  = 7
+)
+
+// codeError 已经确定退出码的错误
+type codeError struct {
+	code int
+	err  error
+}
+
+func (e *codeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *codeError) Unwrap() error {
+	return e.err
+}
+
+// withFallback 接口之外的错误（例如写文件、写数据库）使用 code 作为退出码
+func withFallback(err error, code int) error {
+	if err == nil {
+		return nil
+	}
+	var e *codeError
+	if errors.As(err, &e) || classify(err) != exitError {
+		return err
+	}
+	return &codeError{code: code, err: err}
+}
+
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var e *codeError
+	if errors.As(err, &e) {
+		return e.code
+	}
+	return classify(err)
+}
+
+// classify 根据 sdk 返回的错误类型判断退出码
+func classify(err error) int {
+	var (
+		retrieveErr *oauth2.RetrieveError
+		gatewayErr  *sdk.GatewayError
+		apiErr      *sdk.APIError
+		requestErr  *sdk.RequestError
+	)
+	switch {
+	// 获取 token 时网关拒绝了请求
+	case errors.As(err, &retrieveErr):
+		return exitAuth
+	case errors.As(err, &gatewayErr):
+		if gatewayErr.Auth() {
+			return exitAuth
+		}
+		return exitAPI
+	case errors.As(err, &apiErr):
+		return exitAPI
+	case errors.As(err, &requestErr), errors.Is(err, context.DeadlineExceeded):
+		return exitNetwork
+	}
+	return exitError
+}
@@ -0,0 +1,176 @@
+/*
+ecnu-openapi 是不需要编写代码的命令行工具，用于调用接口、导出数据和同步到数据库
+
+	ecnu-openapi call [-X method] [-H key:value] [-d body] api_path
+	ecnu-openapi rows [-p key=value] [-page-size n] [-limit n] api_path
+	ecnu-openapi export -o file [-format csv|xlsx|jsonl] api_path
+	ecnu-openapi sync -driver sqlite -dsn data.db [-table name] [-key id] [-migrate auto] [-count] api_path
+
+接口的 client_id、client_secret 等配置从 ECNU_ 前缀的环境变量读取，也可以用 -config 指定 .json 或 .env 配置文件。
+
+退出码：
+
+	0 成功
+	1 其他错误
+	2 参数错误
+	3 配置错误
+	4 认证失败，例如 client_id、client_secret 错误或没有接口权限
+	5 接口返回错误，例如 errCode 不为 0 或者网关返回的错误码
+	6 网络错误，例如连接失败或超时
+	7 写入文件或数据库失败
+*/
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk"
+)
+
+const usage = `Usage: ecnu-openapi <command> [flags] api_path
+
+Commands:
+  call     调用任意接口，输出 APIResult
+  rows     分页读取接口数据，每行输出一个 json 对象
+  export   导出接口数据到 csv、xlsx 或 jsonl 文件
+  sync     同步接口数据到数据库
+
+使用 ecnu-openapi <command> -h 查看命令的参数。
+`
+
+type command func(args []string, stdout, stderr io.Writer) error
+
+var commands = map[string]command{
+	"call":   runCall,
+	"rows":   runRows,
+	"export": runExport,
+	"sync":   runSync,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run 执行子命令并返回退出码
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+	if args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "ecnu-openapi: unknown command %s\n\n%s", args[0], usage)
+		return exitUsage
+	}
+	err := cmd(args[1:], stdout, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "ecnu-openapi:", err)
+	}
+	return exitCode(err)
+}
+
+// clientFlags 各个子命令共用的配置参数
+type clientFlags struct {
+	config string
+	prefix string
+}
+
+func (cf *clientFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&cf.config, "config", "", "配置文件（.json 或 .env），默认从环境变量读取")
+	fs.StringVar(&cf.prefix, "env", "ECNU", "环境变量的前缀")
+}
+
+// init 读取配置并初始化全局 client
+func (cf *clientFlags) init() (*sdk.OAuth2Client, error) {
+	var config sdk.OAuth2Config
+	var err error
+	if cf.config != "" {
+		config, err = sdk.LoadConfig(cf.config)
+	} else {
+		config, err = sdk.ConfigFromEnv(cf.prefix)
+	}
+	if err != nil {
+		return nil, &codeError{code: exitConfig, err: err}
+	}
+	if err := sdk.SetupOAuth2ClientCredentials(config); err != nil {
+		return nil, &codeError{code: exitConfig, err: err}
+	}
+	return sdk.GetOpenAPIClient(), nil
+}
+
+// newFlagSet 创建子命令的 FlagSet，解析失败时返回 exitUsage
+func newFlagSet(name, args string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("ecnu-openapi "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: ecnu-openapi %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags 解析参数，并要求剩下一个接口路径
+func parseFlags(fs *flag.FlagSet, args []string) (string, error) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return "", err
+		}
+		return "", &codeError{code: exitUsage, err: err}
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return "", &codeError{code: exitUsage, err: errors.New("api_path is required")}
+	}
+	return fs.Arg(0), nil
+}
+
+// listFlag 可以重复指定的参数
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// split 将 key=value 形式的参数拆分为键值对
+func (l listFlag) split(sep string) ([][2]string, error) {
+	var pairs [][2]string
+	for _, item := range l {
+		key, value, ok := strings.Cut(item, sep)
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, &codeError{code: exitUsage, err: fmt.Errorf("invalid parameter %q, expected key%svalue", item, sep)}
+		}
+		pairs = append(pairs, [2]string{strings.TrimSpace(key), strings.TrimSpace(value)})
+	}
+	return pairs, nil
+}
+
+// values 将 -p 参数转换为 url.Values
+func (l listFlag) values() (url.Values, error) {
+	pairs, err := l.split("=")
+	if err != nil {
+		return nil, err
+	}
+	values := make(url.Values)
+	for _, pair := range pairs {
+		values.Add(pair[0], pair[1])
+	}
+	return values, nil
+}
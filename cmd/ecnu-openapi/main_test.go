@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk"
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk/sdktest"
+	"golang.org/x/oauth2"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testAPIPath = "/api/v1/sync/fakewithts"
+
+// newTestServer 启动模拟网关，并通过环境变量传入配置
+func newTestServer(t *testing.T) *sdktest.Server {
+	t.Helper()
+	srv := sdktest.NewServer()
+	t.Cleanup(srv.Close)
+	t.Setenv("ECNU_CLIENT_ID", srv.ClientId)
+	t.Setenv("ECNU_CLIENT_SECRET", srv.ClientSecret)
+	t.Setenv("ECNU_BASE_URL", srv.URL)
+	var rows []map[string]interface{}
+	for i := 1; i <= 25; i++ {
+		rows = append(rows, map[string]interface{}{
+			"id": i, "updated_at": "2023-01-01 08:00:00", "deleted_mark": 0, "name": fmt.Sprintf("name%d", i),
+		})
+	}
+	if _, err := srv.AddRows(testAPIPath, rows); err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+func runTest(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func Test_Call(t *testing.T) {
+	srv := newTestServer(t)
+	srv.AddData("/api/v1/organization/1", map[string]interface{}{"code": "0445"})
+
+	code, stdout, stderr := runTest("call", "-X", "post", "-H", "X-Test: 1", "-d", `{"a":1}`, "/api/v1/organization/1")
+	if code != exitOK {
+		t.Fatalf("unexpected exit code %d: %s", code, stderr)
+	}
+	var result struct {
+		ErrCode int64
+		Data    map[string]string
+	}
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil || result.Data["code"] != "0445" {
+		t.Errorf("unexpected output: %s", stdout)
+	}
+	if srv.LastHeader("/api/v1/organization/1").Get("X-Test") != "1" {
+		t.Error("expected header to be sent")
+	}
+
+	if code, _, _ := runTest("call", "/api/v1/notfound"); code != exitAPI {
+		t.Errorf("expected exit code %d, got %d", exitAPI, code)
+	}
+	if code, _, _ := runTest("call", "-d", "{", "/api/v1/organization/1"); code != exitUsage {
+		t.Errorf("expected exit code %d, got %d", exitUsage, code)
+	}
+	t.Setenv("ECNU_CLIENT_SECRET", "wrong")
+	if code, _, _ := runTest("call", "/api/v1/organization/1"); code != exitAuth {
+		t.Errorf("expected exit code %d, got %d", exitAuth, code)
+	}
+}
+
+func Test_Rows(t *testing.T) {
+	newTestServer(t)
+	code, stdout, stderr := runTest("rows", "-page-size", "10", testAPIPath)
+	if code != exitOK {
+		t.Fatalf("unexpected exit code %d: %s", code, stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 25 || !strings.Contains(lines[24], `"id":25`) {
+		t.Errorf("unexpected rows: %d\n%s", len(lines), stdout)
+	}
+
+	_, stdout, _ = runTest("rows", "-page-size", "10", "-limit", "3", testAPIPath)
+	if n := strings.Count(stdout, "\n"); n != 3 {
+		t.Errorf("expected 3 rows, got %d", n)
+	}
+}
+
+func Test_Export(t *testing.T) {
+	newTestServer(t)
+	output := filepath.Join(t.TempDir(), "rows.csv")
+	code, _, stderr := runTest("export", "-o", output, testAPIPath)
+	if code != exitOK {
+		t.Fatalf("unexpected exit code %d: %s", code, stderr)
+	}
+	content, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 表头加 25 行数据
+	if n := strings.Count(string(content), "\n"); n != 26 {
+		t.Errorf("expected 26 lines, got %d", n)
+	}
+
+	if code, _, _ := runTest("export", "-o", "rows.txt", testAPIPath); code != exitUsage {
+		t.Errorf("expected exit code %d, got %d", exitUsage, code)
+	}
+	dir := filepath.Join(t.TempDir(), "notfound", "rows.csv")
+	if code, _, _ := runTest("export", "-o", dir, testAPIPath); code != exitOutput {
+		t.Errorf("expected exit code %d, got %d", exitOutput, code)
+	}
+}
+
+func Test_Sync(t *testing.T) {
+	newTestServer(t)
+	dsn := filepath.Join(t.TempDir(), "test.db")
+	for i := 0; i < 2; i++ {
+		code, _, stderr := runTest("sync", "-dsn", dsn, "-table", "fake_rows", "-page-size", "10", testAPIPath)
+		if code != exitOK {
+			t.Fatalf("unexpected exit code %d: %s", code, stderr)
+		}
+	}
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total int64
+	db.Table("fake_rows").Count(&total)
+	if total != 25 {
+		t.Errorf("expected 25 rows, got %d", total)
+	}
+
+	// 只检查表结构时不会创建表
+	if code, _, stderr := runTest("sync", "-dsn", dsn, "-table", "other_rows", "-migrate", "verify", testAPIPath); code != exitOutput ||
+		!strings.Contains(stderr, "table not exists") {
+		t.Errorf("expected exit code %d, got %d: %s", exitOutput, code, stderr)
+	}
+	if code, _, _ := runTest("sync", "-driver", "oracle", "-dsn", dsn, testAPIPath); code != exitUsage {
+		t.Errorf("expected exit code %d, got %d", exitUsage, code)
+	}
+}
+
+func Test_ExitCode(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		code int
+	}{
+		{nil, exitOK},
+		{errors.New("rows is empty"), exitError},
+		{&sdk.RequestError{Method: "GET", Err: &url.Error{Op: "Get", URL: "http://x", Err: &oauth2.RetrieveError{}}}, exitAuth},
+		{&sdk.GatewayError{StatusCode: 403, Code: "A403PR"}, exitAuth},
+		{&sdk.GatewayError{StatusCode: 500, Code: "X500ER"}, exitAPI},
+		{fmt.Errorf("sync fail: %w", &sdk.APIError{ErrCode: 1}), exitAPI},
+		{&sdk.RequestError{Method: "GET", Err: errors.New("dial tcp: connection refused")}, exitNetwork},
+		{withFallback(errors.New("database is locked"), exitOutput), exitOutput},
+		{withFallback(&sdk.RequestError{Method: "GET", Err: io.EOF}, exitOutput), exitNetwork},
+		// 错误信息与 sdk 的格式相同，但不是 sdk 返回的错误
+		{errors.New("invoke api get fail, X-Ca-Error-Code: A403PR"), exitError},
+	} {
+		if code := exitCode(tt.err); code != tt.code {
+			t.Errorf("exitCode(%v) = %d, expected %d", tt.err, code, tt.code)
+		}
+	}
+	if code, _, _ := runTest("unknown"); code != exitUsage {
+		t.Errorf("expected exit code %d, got %d", exitUsage, code)
+	}
+	t.Setenv("ECNU_CLIENT_ID", "")
+	if code, _, _ := runTest("rows", testAPIPath); code != exitConfig {
+		t.Errorf("expected exit code %d, got %d", exitConfig, code)
+	}
+}
+
+func Test_ExitCodeFromServer(t *testing.T) {
+	srv := newTestServer(t)
+	srv.InjectFault(sdktest.GatewayError(testAPIPath, 500, "X500ER", "error"))
+	if code, _, stderr := runTest("rows", testAPIPath); code != exitAPI {
+		t.Errorf("expected exit code %d, got %d: %s", exitAPI, code, stderr)
+	}
+
+	t.Setenv("ECNU_CLIENT_SECRET", "wrong-secret")
+	if code, _, stderr := runTest("rows", testAPIPath); code != exitAuth {
+		t.Errorf("expected exit code %d, got %d: %s", exitAuth, code, stderr)
+	}
+
+	// 网关不可用
+	srv.Close()
+	if code, _, stderr := runTest("rows", testAPIPath); code != exitNetwork {
+		t.Errorf("expected exit code %d, got %d: %s", exitNetwork, code, stderr)
+	}
+}
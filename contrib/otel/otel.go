@@ -0,0 +1,87 @@
+/*
+Package ecnuotel 将 SDK 的链路追踪接入 OpenTelemetry
+
+	import ecnuotel "github.com/ecnu/ecnu-openapi-sdk-go/contrib/otel"
+
+	cf.Tracer = ecnuotel.NewTracer(otel.GetTracerProvider())
+
+该包是独立的 go module，只有需要 OpenTelemetry 时才会引入相关依赖。
+*/
+package ecnuotel
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName OpenTelemetry instrumentation 名称
+const InstrumentationName = "github.com/ecnu/ecnu-openapi-sdk-go"
+
+type tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer 使用 TracerProvider 创建 sdk.Tracer
+func NewTracer(tp trace.TracerProvider) sdk.Tracer {
+	return tracer{tracer: tp.Tracer(InstrumentationName)}
+}
+
+func (t tracer) Start(ctx context.Context, name string, attrs ...sdk.Attribute) (context.Context, sdk.Span) {
+	kind := trace.SpanKindInternal
+	if name == sdk.SpanHTTP || name == sdk.SpanToken {
+		kind = trace.SpanKindClient
+	}
+	ctx, s := t.tracer.Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(convert(attrs)...))
+	return ctx, span{span: s}
+}
+
+type span struct {
+	span trace.Span
+}
+
+func (s span) SetAttributes(attrs ...sdk.Attribute) {
+	s.span.SetAttributes(convert(attrs)...)
+}
+
+func (s span) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s span) End() {
+	s.span.End()
+}
+
+func (s span) TraceParent() string {
+	sc := s.span.SpanContext()
+	if !sc.IsValid() {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID(), sc.SpanID(), sc.TraceFlags())
+}
+
+func convert(attrs []sdk.Attribute) []attribute.KeyValue {
+	res := make([]attribute.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		switch v := attr.Value.(type) {
+		case string:
+			res = append(res, attribute.String(attr.Key, v))
+		case int:
+			res = append(res, attribute.Int(attr.Key, v))
+		case int64:
+			res = append(res, attribute.Int64(attr.Key, v))
+		case bool:
+			res = append(res, attribute.Bool(attr.Key, v))
+		case float64:
+			res = append(res, attribute.Float64(attr.Key, v))
+		default:
+			res = append(res, attribute.String(attr.Key, fmt.Sprint(v)))
+		}
+	}
+	return res
+}
@@ -0,0 +1,41 @@
+package ecnuotel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func Test_Tracer(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	tracer := NewTracer(tp)
+
+	ctx, parent := tracer.Start(context.Background(), sdk.SpanSync, sdk.Attr(sdk.AttrAPIPath, "/api/v1/sync/fakewithts"))
+	_, child := tracer.Start(ctx, sdk.SpanHTTP, sdk.Attr(sdk.AttrPageNum, 1))
+	child.SetAttributes(sdk.Attr(sdk.AttrRows, int64(10)))
+	traceParent := child.TraceParent()
+	child.RecordError(errors.New("gateway down"))
+	child.End()
+	parent.End()
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	httpSpan := spans[0]
+	if httpSpan.Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Error("http span should be a child of the sync span")
+	}
+	want := "00-" + httpSpan.SpanContext().TraceID().String() + "-" + httpSpan.SpanContext().SpanID().String() + "-01"
+	if traceParent != want {
+		t.Errorf("traceparent should be %q, got %q", want, traceParent)
+	}
+	if len(httpSpan.Attributes()) != 2 || httpSpan.Status().Description != "gateway down" {
+		t.Errorf("unexpected span: %v %v", httpSpan.Attributes(), httpSpan.Status())
+	}
+}
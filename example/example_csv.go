@@ -28,10 +28,10 @@ func exampleSyncToCSV() {
 	// csv 模式下，所有字段都会转为 string
 	csvFile := "test.csv"
 	mode := "csv"
-	rows, err := sdk.SyncToFile(mode, csvFile, api)
+	res, err := sdk.SyncToFile(mode, csvFile, api)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
-	fmt.Printf("CSV：已同步 %d 条数据\n", rows)
+	fmt.Printf("CSV：已同步 %d 条数据\n", res.Rows)
 }
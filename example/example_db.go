@@ -73,9 +73,13 @@ func exampleSyncToDB() {
 
 	fakeRows := []FakeRowsWithTS{}
 
-	// 如果接口不支持软删除标记，且需要删除上游已删除的数据，可以先删除表，再全量同步
-	// 如果希望自己在同步时建立软删除标记，可以建立临时表进行全量同步
-	// 再将临时表的数据更新到主表，并对比数据建立软删除标记。
+	// 默认在同步前自动迁移表结构，由 DBA 管理表结构时可以设置 api.Migrate = sdk.MigrateVerify 只做检查，
+	// 设置 api.Table 可以把同一个模型同步到不同的表
+
+	// 如果接口不支持软删除标记，且需要删除上游已删除的数据，可以使用 sdk.SyncFullRefreshToDB，
+	// 先同步到临时表，再在一个事务中替换正式表；也可以先删除表，再全量同步
+	// 如果希望在同步时建立软删除标记，可以在全量同步时设置 api.Reconcile，
+	// 同步结束后对比主键，标记本次没有同步到的数据，详见 sdk.ReconcileOptions。
 	/*
 		if err = db.Migrator().DropTable(rows); err != nil {
 			return
@@ -85,12 +89,12 @@ func exampleSyncToDB() {
 	// 首次同步时，添加参数 ts=0，同步当前全部有效数据
 	// 如果未创建表会自动根据 model 建表
 	api.SetParam("ts", "0")
-	rowsCount, err := sdk.SyncToDB(db, api, &fakeRows)
+	res, err := sdk.SyncToDB(db, api, &fakeRows)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
-	fmt.Printf("DB:首次同步，从接口获取到 %d 条数据\n", rowsCount)
+	fmt.Printf("DB:首次同步，从接口获取到 %d 条数据\n", res.Rows)
 
 	// 获取数据库内最后一条时间戳
 	ts := sdk.GetLastUpdatedTS(db, api, FakeRowsWithTS{})
@@ -98,16 +102,21 @@ func exampleSyncToDB() {
 
 	// 参照接口文档，添加full参数，获取包含删除的数据
 	// 因此可以捕捉上游数据删除的情况，以软删除的形式记录到数据库
+	// 设置 api.DeleteMarker 后，带有删除标记的数据会从表中删除（或使用 gorm 的软删除），详见 sdk.DeleteMarker
+	// api.DeleteMarker = &sdk.DeleteMarker{Field: "deleted_mark", HardDelete: true}
 
 	api.SetParam("ts", fmt.Sprintf("%d", ts))
 	api.SetParam("full", "1")
 
 	fakeRows = []FakeRowsWithTS{}
-	rowsCount, err = sdk.SyncToDB(db, api, &fakeRows)
+	// 设置 CountChanges 后统计新增、更新的行数
+	api.CountChanges = true
+	res, err = sdk.SyncToDB(db, api, &fakeRows)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
-	fmt.Printf("DB:增量同步，从接口获取到 %d 条数据\n", rowsCount)
+	// SyncResult 中还有新增、更新、删除的行数和各阶段的耗时，详见 sdk.SyncResult
+	fmt.Printf("DB:增量同步，从接口获取到 %d 条数据，新增 %d 条，更新 %d 条，删除 %d 条\n", res.Rows, res.Inserted, res.Updated, res.Deleted)
 
 }
@@ -14,7 +14,7 @@ func exampleSyncToModel() {
 	}
 	api.SetParam("ts", "0")
 	fakeRows := []FakeRowsWithTS{}
-	if err := sdk.SyncToModel(api, &fakeRows); err != nil {
+	if _, err := sdk.SyncToModel(api, &fakeRows); err != nil {
 		fmt.Println(err)
 		return
 	}
@@ -34,7 +34,7 @@ func exampleSyncToModel() {
 	api.SetParam("full", "1")
 
 	fakeRows = []FakeRowsWithTS{}
-	if err := sdk.SyncToModel(api, &fakeRows); err != nil {
+	if _, err := sdk.SyncToModel(api, &fakeRows); err != nil {
 		fmt.Println(err)
 		return
 	}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -21,9 +22,15 @@ func exampleCallAPIPerformance(api sdk.APIConfig) {
 	startTime := time.Now().UnixMilli()
 
 	fmt.Printf("单次接口调用开始，pageSize=%d\n", api.PageSize)
-	apiPath := api.APIPath + "?" + api.ParamEncode()
 	c := sdk.GetOpenAPIClient()
-	_, err := c.GetRows(apiPath, 1, api.PageSize)
+	var page sdk.DataResult
+	_, err := c.R().
+		Path(api.APIPath).
+		Queries(api.Params()).
+		Query("pageNum", 1).
+		Query("pageSize", api.PageSize).
+		Into(&page).
+		Do(context.Background())
 	if err != nil {
 		fmt.Println(err)
 		return
@@ -43,7 +50,7 @@ func exampleSyncPerformanceByModel(api sdk.APIConfig) {
 	initMem := m.Alloc // 获取初始分配的内存字节数
 
 	fmt.Printf("Model:首次同步开始\n")
-	if err := sdk.SyncToModel(api, &fakeRows); err != nil {
+	if _, err := sdk.SyncToModel(api, &fakeRows); err != nil {
 		fmt.Println(err)
 		return
 	}
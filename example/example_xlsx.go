@@ -28,10 +28,10 @@ func exampleSyncToXLSX() {
 	// xlsx 模式下，所有字段都会转为 string
 	xlsxFile := "test.xlsx"
 	mode := "xlsx"
-	rows, err := sdk.SyncToFile(mode, xlsxFile, api)
+	res, err := sdk.SyncToFile(mode, xlsxFile, api)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
-	fmt.Printf("XLSX：组织机构同步 %d 条数据\n", rows)
+	fmt.Printf("XLSX：组织机构同步 %d 条数据\n", res.Rows)
 }
@@ -1,9 +1,8 @@
 package sdk
 
 import (
-	"encoding/json"
-	"fmt"
-	"strings"
+	"context"
+	"time"
 )
 
 // DataResult
@@ -16,31 +15,75 @@ type DataResult struct {
 
 // GetRows
 func (c *OAuth2Client) GetRows(apiPath string, pageNum, pageSize int) (DataResult, error) {
+	dataResult, _, err := c.getPage(context.Background(), apiPath, pageNum, pageSize)
+	return dataResult, err
+}
+
+func (c *OAuth2Client) getPage(ctx context.Context, apiPath string, pageNum, pageSize int) (DataResult, *Response, error) {
 	var dataResult DataResult
-	var url string
-	if strings.Contains(apiPath, "?") {
-		url = fmt.Sprintf("%s%s&pageNum=%d&pageSize=%d", c.BaseUrl, apiPath, pageNum, pageSize)
+	resp, err := c.R().
+		Path(apiPath).
+		Query("pageNum", pageNum).
+		Query("pageSize", pageSize).
+		Into(&dataResult).
+		Do(ctx)
+	return dataResult, resp, err
+}
+
+// GetAllRows
+func (c *OAuth2Client) GetAllRows(apiPath string, pageSize int) ([]interface{}, error) {
+	return getAllRows(context.Background(), c, apiPath, pageSize, &SyncResult{})
+}
+
+// pageRequester 支持传递 context 并返回响应的 Requester，用于链路追踪和 SyncResult
+type pageRequester interface {
+	getPage(ctx context.Context, apiPath string, pageNum, pageSize int) (DataResult, *Response, error)
+}
+
+// fetchPage 获取一页数据，并为其创建 span，请求的页数、行数、字节数和耗时记录到 res
+func fetchPage(ctx context.Context, r Requester, apiPath string, pageNum, pageSize int, res *SyncResult) (DataResult, error) {
+	start := time.Now()
+	ctx, span := tracerOf(r).Start(ctx, SpanPageFetch,
+		Attr(AttrAPIPath, apiPath),
+		Attr(AttrPageNum, pageNum),
+		Attr(AttrPageSize, pageSize),
+	)
+	ctx = withPageRequest(ctx)
+	var result DataResult
+	var resp *Response
+	var err error
+	if pr, ok := r.(pageRequester); ok {
+		result, resp, err = pr.getPage(ctx, apiPath, pageNum, pageSize)
 	} else {
-		url = fmt.Sprintf("%s%s?pageNum=%d&pageSize=%d", c.BaseUrl, apiPath, pageNum, pageSize)
+		result, err = r.GetRows(apiPath, pageNum, pageSize)
 	}
+	span.SetAttributes(Attr(AttrRows, len(result.Rows)))
+	endSpan(span, err)
 
-	data, err := c.HttpGet(url)
+	res.FetchDuration += time.Since(start)
+	if resp != nil {
+		res.Bytes += resp.Size
+	}
 	if err != nil {
-		return dataResult, err
+		if resp != nil && resp.RequestId != "" {
+			res.FailedRequestIds = append(res.FailedRequestIds, resp.RequestId)
+		}
+		return result, err
 	}
-
-	if err := json.Unmarshal(data, &dataResult); err != nil {
-		return dataResult, err
+	res.Pages++
+	if res.Pages == 1 {
+		res.TotalNum = result.TotalNum
 	}
-	return dataResult, err
+	res.Rows += int64(len(result.Rows))
+	return result, nil
 }
 
-// GetAllRows
-func (c *OAuth2Client) GetAllRows(apiPath string, pageSize int) ([]interface{}, error) {
+// getAllRows 读取全部数据
+func getAllRows(ctx context.Context, r Requester, apiPath string, pageSize int, res *SyncResult) ([]interface{}, error) {
 	var rows []interface{}
 	pageNum := 1
 	for {
-		result, err := c.GetRows(apiPath, pageNum, pageSize)
+		result, err := fetchPage(ctx, r, apiPath, pageNum, pageSize, res)
 		if err != nil {
 			return rows, err
 		}
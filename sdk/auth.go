@@ -1,6 +1,7 @@
 package sdk
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
@@ -17,8 +18,8 @@ import (
 
 var (
 	userInfoURL string
+	authCtx     = context.Background()
 	config      *oauth2.Config
-	client      *http.Client
 	authLock    = new(sync.RWMutex)
 	c           *cache.Cache
 )
@@ -34,22 +35,33 @@ type UserInfoResponse struct {
 	} `json:"data"`
 }
 
+// InitOAuth2AuthorizationCode 初始化授权码模式，配置有误时通过 log 输出错误，需要处理错误时使用 SetupOAuth2AuthorizationCode
 func InitOAuth2AuthorizationCode(cf OAuth2Config) {
-	scopes := []string{DefaultScope}
-	authURL := DefaultAuthURL
-	tokenURL := DefaultTokenURL
-	userInfoURL = DefaultUserInfoURL
-	if len(cf.Scopes) > 0 {
-		scopes = cf.Scopes
+	if err := SetupOAuth2AuthorizationCode(cf); err != nil {
+		log.Printf("初始化失败: %v", err)
 	}
-	if cf.UserInfoURL != "" {
-		userInfoURL = cf.UserInfoURL
+}
+
+// SetupOAuth2AuthorizationCode 与 InitOAuth2AuthorizationCode 相同，配置有误时返回错误
+func SetupOAuth2AuthorizationCode(cf OAuth2Config) error {
+	profile, baseUrls, err := cf.resolveProfile()
+	if err != nil {
+		return err
 	}
-	if cf.Endpoint.AuthURL != "" {
-		authURL = cf.Endpoint.AuthURL
+	ctx, err := newHTTPContext(cf, baseUrls)
+	if err != nil {
+		return err
 	}
-	if cf.Endpoint.TokenURL != "" {
-		tokenURL = cf.Endpoint.TokenURL
+	authLock.Lock()
+	defer authLock.Unlock()
+
+	scopes := []string{DefaultScope}
+	authURL := profile.AuthURL
+	tokenURL := profile.TokenURL
+	userInfoURL = profile.UserInfoURL
+	authCtx = ctx
+	if len(cf.Scopes) > 0 {
+		scopes = cf.Scopes
 	}
 
 	config = &oauth2.Config{
@@ -76,7 +88,7 @@ func InitOAuth2AuthorizationCode(cf OAuth2Config) {
 		cleanup = cf.Cache.Cleanup
 	}
 	c = cache.New(expiration, cleanup)
-
+	return nil
 }
 
 func GetAuthorizationEndpoint(state string) string {
@@ -98,7 +110,7 @@ func GetToken(code string, state string) (*oauth2.Token, error) {
 	if !found {
 		return nil, fmt.Errorf("state有误")
 	}
-	token, err := config.Exchange(oauth2.NoContext, code)
+	token, err := config.Exchange(authCtx, code)
 	if err != nil {
 		fmt.Println("获取token失败")
 		return nil, err
@@ -137,9 +149,8 @@ func GetUserInfo(client *http.Client) (UserInfoResponse, error) {
 
 func GetClient(token *oauth2.Token) *http.Client {
 	authLock.RLock()
-	client = config.Client(oauth2.NoContext, token)
 	defer authLock.RUnlock()
-	return client
+	return config.Client(authCtx, token)
 }
 
 func GenerateState() string {
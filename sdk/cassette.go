@@ -0,0 +1,318 @@
+package sdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	CassetteRecord = "record"
+	CassetteReplay = "replay"
+
+	redacted = "REDACTED"
+)
+
+var (
+	// 录制时需要脱敏的请求/响应头
+	redactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+	// 录制时需要脱敏的 form 参数和 json 字段
+	redactedFields = []string{"client_secret", "access_token", "refresh_token", "id_token", "code"}
+)
+
+/*
+CassetteConfig 录制/回放配置
+
+record 模式下，所有请求（包括获取 token）都会真实发出，并将请求和响应写入 Path 指定的文件，token 和 secret 会被脱敏。
+replay 模式下，不会发出任何网络请求，只从文件中查找匹配的响应，找不到时请求失败。
+*/
+type CassetteConfig struct {
+	Mode string `json:"mode"`
+	Path string `json:"path"`
+
+	// IgnoreQuery 匹配时忽略的 query 参数，例如 ts
+	IgnoreQuery []string `json:"ignore_query"`
+	// IgnoreQueryOrder 匹配时忽略 query 参数的顺序
+	IgnoreQueryOrder bool `json:"ignore_query_order"`
+	// IgnoreHost 匹配时忽略 scheme 和 host，便于将生产环境录制的文件用于其他地址
+	IgnoreHost bool `json:"ignore_host"`
+	// MatchBody 匹配时比对请求体
+	MatchBody bool `json:"match_body"`
+}
+
+// Cassette 录制文件的内容
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// NewCassetteTransport 根据配置返回录制或回放的 RoundTripper，Mode 为空时原样返回 next
+func NewCassetteTransport(cf CassetteConfig, next http.RoundTripper) (http.RoundTripper, error) {
+	switch cf.Mode {
+	case "":
+		return next, nil
+	case CassetteRecord:
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		return &recorder{conf: cf, next: next}, nil
+	case CassetteReplay:
+		content, err := os.ReadFile(cf.Path)
+		if err != nil {
+			return nil, fmt.Errorf("read cassette fail: %v", err)
+		}
+		r := &replayer{conf: cf}
+		if err := json.Unmarshal(content, &r.cassette); err != nil {
+			return nil, fmt.Errorf("parse cassette %s fail: %v", cf.Path, err)
+		}
+		r.played = make([]bool, len(r.cassette.Interactions))
+		return r, nil
+	}
+	return nil, fmt.Errorf("not support cassette mode: %s, only record or replay", cf.Mode)
+}
+
+type recorder struct {
+	conf CassetteConfig
+	next http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := readBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    redactURL(req.URL),
+			Header: redactHeader(req.Header),
+			Body:   redactBody(reqBody),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     redactHeader(resp.Header),
+			Body:       redactBody(respBody),
+		},
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	content, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(r.conf.Path, content, 0644); err != nil {
+		return nil, fmt.Errorf("write cassette fail: %v", err)
+	}
+	return resp, nil
+}
+
+type replayer struct {
+	conf CassetteConfig
+
+	mu       sync.Mutex
+	cassette Cassette
+	played   []bool
+}
+
+// RoundTrip 优先返回未回放过的匹配记录，全部回放过时重复使用最后一条匹配的记录
+func (r *replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	key := r.conf.matchKey(req.Method, redactURL(req.URL), redactBody(reqBody))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	matched := -1
+	for i, interaction := range r.cassette.Interactions {
+		recorded := interaction.Request
+		if r.conf.matchKey(recorded.Method, recorded.URL, recorded.Body) != key {
+			continue
+		}
+		matched = i
+		if !r.played[i] {
+			break
+		}
+	}
+	if matched < 0 {
+		return nil, fmt.Errorf("cassette %s: no interaction matches %s %s", r.conf.Path, req.Method, req.URL.String())
+	}
+	r.played[matched] = true
+
+	recorded := r.cassette.Interactions[matched].Response
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorded.Header.Clone(),
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+func (cf CassetteConfig) matchKey(method, rawURL, body string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return method + " " + rawURL
+	}
+	if cf.IgnoreHost {
+		u.Scheme = ""
+		u.Host = ""
+	}
+	if len(cf.IgnoreQuery) > 0 || cf.IgnoreQueryOrder {
+		query := u.Query()
+		for _, key := range cf.IgnoreQuery {
+			query.Del(key)
+		}
+		// Encode 会按 key 排序，因此只在忽略顺序时使用
+		if cf.IgnoreQueryOrder {
+			u.RawQuery = query.Encode()
+		} else {
+			u.RawQuery = removeQueryKeys(u.RawQuery, cf.IgnoreQuery)
+		}
+	}
+	key := method + " " + u.String()
+	if cf.MatchBody {
+		key = key + "\n" + body
+	}
+	return key
+}
+
+func removeQueryKeys(rawQuery string, keys []string) string {
+	var parts []string
+	for _, part := range strings.Split(rawQuery, "&") {
+		name, _, _ := strings.Cut(part, "=")
+		if name, err := url.QueryUnescape(name); err == nil && containsString(keys, name) {
+			continue
+		}
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// readBody 读取并还原 body，使其可以被再次读取
+func readBody(body *io.ReadCloser) (string, error) {
+	if *body == nil || *body == http.NoBody {
+		return "", nil
+	}
+	content, err := io.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return "", err
+	}
+	*body = io.NopCloser(bytes.NewReader(content))
+	return string(content), nil
+}
+
+func redactHeader(header http.Header) http.Header {
+	h := header.Clone()
+	for _, key := range redactedHeaders {
+		if h.Get(key) != "" {
+			h.Set(key, redacted)
+		}
+	}
+	return h
+}
+
+func redactURL(u *url.URL) string {
+	clone := *u
+	query := clone.Query()
+	changed := false
+	for _, key := range redactedFields {
+		if query.Has(key) {
+			query.Set(key, redacted)
+			changed = true
+		}
+	}
+	if changed {
+		clone.RawQuery = query.Encode()
+	}
+	return clone.String()
+}
+
+// redactBody 对 json 对象和 form 表单中的 token、secret 脱敏，其他内容原样保留
+func redactBody(body string) string {
+	if body == "" {
+		return body
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(body), &obj); err == nil {
+		changed := false
+		for _, key := range redactedFields {
+			if _, ok := obj[key]; ok {
+				obj[key] = json.RawMessage(`"` + redacted + `"`)
+				changed = true
+			}
+		}
+		if !changed {
+			return body
+		}
+		content, _ := json.Marshal(obj)
+		return string(content)
+	}
+	if form, err := url.ParseQuery(body); err == nil && strings.Contains(body, "=") {
+		changed := false
+		for _, key := range redactedFields {
+			if form.Has(key) {
+				form.Set(key, redacted)
+				changed = true
+			}
+		}
+		if changed {
+			return form.Encode()
+		}
+	}
+	return body
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
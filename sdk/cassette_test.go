@@ -0,0 +1,85 @@
+package sdk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_CassetteRecordAndReplay(t *testing.T) {
+	srv := newTestServer(t)
+	if _, err := srv.AddRows(testAPIPath, newTestRows(15, "2023-01-02 00:00:00")); err != nil {
+		t.Fatal(err)
+	}
+	cassetteFile := filepath.Join(t.TempDir(), "fakewithts.json")
+
+	err := SetupOAuth2ClientCredentials(OAuth2Config{
+		ClientId:     srv.ClientId,
+		ClientSecret: srv.ClientSecret,
+		BaseUrl:      srv.URL,
+		Cassette:     CassetteConfig{Mode: CassetteRecord, Path: cassetteFile},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := APIConfig{APIPath: testAPIPath, PageSize: 10}
+	api.SetParam("ts", "0")
+	if _, err := SyncToDB(newTestDB(t), api, &[]testFakeRow{}); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(cassetteFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), srv.ClientSecret) {
+		t.Error("client secret should be redacted")
+	}
+	if !strings.Contains(string(content), `\"access_token\":\"REDACTED\"`) || strings.Contains(string(content), "Bearer ") {
+		t.Error("access token should be redacted")
+	}
+
+	// 关闭网关后回放，ts 参数不同也能匹配
+	srv.Close()
+	err = SetupOAuth2ClientCredentials(OAuth2Config{
+		ClientId:     "id",
+		ClientSecret: "secret",
+		BaseUrl:      "https://api.example.com",
+		Cassette: CassetteConfig{
+			Mode:        CassetteReplay,
+			Path:        cassetteFile,
+			IgnoreQuery: []string{"ts"},
+			IgnoreHost:  true,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.SetParam("ts", "1672675200")
+	res, err := SyncToDB(newTestDB(t), api, &[]testFakeRow{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Rows != 15 {
+		t.Errorf("replay should get 15 rows, got %d", res.Rows)
+	}
+
+	if _, err := GetOpenAPIClient().GetRows("/api/v1/unknown", 1, 10); err == nil || !strings.Contains(err.Error(), "no interaction matches") {
+		t.Errorf("unmatched request should fail, got %v", err)
+	}
+}
+
+func Test_CassetteMatchKey(t *testing.T) {
+	cf := CassetteConfig{IgnoreQuery: []string{"ts"}}
+	if cf.matchKey("GET", "https://a/api?ts=1&pageNum=1", "") != cf.matchKey("GET", "https://a/api?pageNum=1&ts=2", "") {
+		t.Error("ts should be ignored")
+	}
+	if cf.matchKey("GET", "https://a/api?pageNum=1&pageSize=2", "") == cf.matchKey("GET", "https://a/api?pageSize=2&pageNum=1", "") {
+		t.Error("query order should be kept by default")
+	}
+	cf.IgnoreQueryOrder = true
+	if cf.matchKey("GET", "https://a/api?pageNum=1&pageSize=2", "") != cf.matchKey("GET", "https://a/api?pageSize=2&pageNum=1", "") {
+		t.Error("query order should be ignored")
+	}
+}
@@ -0,0 +1,78 @@
+package sdk
+
+import (
+	"encoding/json"
+	"io"
+
+	"gorm.io/gorm"
+)
+
+// Requester 接口调用，OAuth2Client 和 MemoryClient 都实现了该接口，便于在测试中替换
+type Requester interface {
+	HttpGet(url string) (json.RawMessage, error)
+	HttpRequest(url, method string, header map[string]string, body io.Reader) (json.RawMessage, error)
+	GetRows(apiPath string, pageNum, pageSize int) (DataResult, error)
+}
+
+// Syncer 数据同步
+type Syncer interface {
+	SyncToFile(mode string, fileName string, api APIConfig) (SyncResult, error)
+	SyncToModel(api APIConfig, dataModel interface{}) (SyncResult, error)
+	SyncToDB(db *gorm.DB, api APIConfig, dataModel interface{}) (SyncResult, error)
+	SyncIncrementalToDB(db *gorm.DB, api APIConfig, dataModel interface{}, opts IncrementalOptions) (SyncResult, error)
+	SyncFullRefreshToDB(db *gorm.DB, api APIConfig, dataModel interface{}) (SyncResult, error)
+}
+
+var (
+	_ Requester = (*OAuth2Client)(nil)
+	_ Syncer    = (*OAuth2Client)(nil)
+)
+
+// NewSyncer 使用任意 Requester 进行数据同步
+func NewSyncer(r Requester) Syncer {
+	return requesterSyncer{r: r}
+}
+
+type requesterSyncer struct {
+	r Requester
+}
+
+func (s requesterSyncer) SyncToFile(mode string, fileName string, api APIConfig) (SyncResult, error) {
+	return syncToFile(s.r, mode, fileName, api)
+}
+
+func (s requesterSyncer) SyncToModel(api APIConfig, dataModel interface{}) (SyncResult, error) {
+	return syncToModel(s.r, api, dataModel)
+}
+
+func (s requesterSyncer) SyncToDB(db *gorm.DB, api APIConfig, dataModel interface{}) (SyncResult, error) {
+	return syncToDB(s.r, db, api, dataModel)
+}
+
+func (s requesterSyncer) SyncIncrementalToDB(db *gorm.DB, api APIConfig, dataModel interface{}, opts IncrementalOptions) (SyncResult, error) {
+	return syncIncrementalToDB(s.r, db, api, dataModel, opts)
+}
+
+func (s requesterSyncer) SyncFullRefreshToDB(db *gorm.DB, api APIConfig, dataModel interface{}) (SyncResult, error) {
+	return syncFullRefreshToDB(s.r, db, api, dataModel)
+}
+
+func (c *OAuth2Client) SyncToFile(mode string, fileName string, api APIConfig) (SyncResult, error) {
+	return syncToFile(c, mode, fileName, api)
+}
+
+func (c *OAuth2Client) SyncToModel(api APIConfig, dataModel interface{}) (SyncResult, error) {
+	return syncToModel(c, api, dataModel)
+}
+
+func (c *OAuth2Client) SyncToDB(db *gorm.DB, api APIConfig, dataModel interface{}) (SyncResult, error) {
+	return syncToDB(c, db, api, dataModel)
+}
+
+func (c *OAuth2Client) SyncIncrementalToDB(db *gorm.DB, api APIConfig, dataModel interface{}, opts IncrementalOptions) (SyncResult, error) {
+	return syncIncrementalToDB(c, db, api, dataModel, opts)
+}
+
+func (c *OAuth2Client) SyncFullRefreshToDB(db *gorm.DB, api APIConfig, dataModel interface{}) (SyncResult, error) {
+	return syncFullRefreshToDB(c, db, api, dataModel)
+}
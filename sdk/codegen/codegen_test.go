@@ -0,0 +1,121 @@
+package codegen
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 修改生成逻辑后使用 go test ./sdk/codegen -update 更新 internal 下生成的代码
+var update = flag.Bool("update", false, "update generated packages under internal")
+
+func Test_GoName(t *testing.T) {
+	cases := map[string]string{
+		"id":             "Id",
+		"userId":         "UserId",
+		"created_at":     "CreatedAt",
+		"X-Tenant":       "XTenant",
+		"FakeRowsWithTS": "FakeRowsWithTS",
+		"listFakeWithTS": "ListFakeWithTS",
+		"USER_NAME":      "UserName",
+		"student info":   "StudentInfo",
+		"2fa":            "F2fa",
+		"":               "Field",
+	}
+	for name, want := range cases {
+		if got := GoName(name); got != want {
+			t.Errorf("GoName(%q) expected %s, got %s", name, want, got)
+		}
+	}
+}
+
+func Test_FieldTag(t *testing.T) {
+	cases := []struct {
+		field Field
+		name  string
+		tag   string
+	}{
+		{Field{JSONName: "id", Type: "int", PrimaryKey: true}, "Id", `json:"id" gorm:"primarykey;autoIncrement:false"`},
+		{Field{JSONName: "userId", Type: "string", PrimaryKey: true}, "UserId", `json:"userId" gorm:"primarykey"`},
+		{Field{JSONName: "updated_at", Type: "time.Time", Datetime: true}, "UpdateTime", `json:"updated_at" time_format:"sql_datetime" time_location:"shanghai" gorm:"index;column:updated_at"`},
+		{Field{JSONName: "deletedAt", Type: "time.Time"}, "DeleteTime", `json:"deletedAt" gorm:"column:deleted_at"`},
+		{Field{JSONName: "tags", Type: "[]string", Serializer: true}, "Tags", `json:"tags" gorm:"serializer:json"`},
+	}
+	for _, cs := range cases {
+		if name, _ := cs.field.fieldName(); name != cs.name {
+			t.Errorf("%s: expected field name %s, got %s", cs.field.JSONName, cs.name, name)
+		}
+		if tag := cs.field.Tag(); tag != cs.tag {
+			t.Errorf("%s: expected tag %s, got %s", cs.field.JSONName, cs.tag, tag)
+		}
+	}
+}
+
+func Test_Generate(t *testing.T) {
+	for _, name := range []string{"openapi3", "swagger2"} {
+		spec, err := os.ReadFile(filepath.Join("testdata", name+".json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Generate(spec, Options{Package: name})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		golden := filepath.Join("internal", name, "api.go")
+		if *update {
+			if err := os.WriteFile(golden, got, 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: generated code differs from %s, run go test ./sdk/codegen -update and review the diff", name, golden)
+		}
+	}
+}
+
+func Test_GenerateOptions(t *testing.T) {
+	spec, err := os.ReadFile(filepath.Join("testdata", "openapi3.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := Generate(spec, Options{Package: "orgapi", Keys: []string{"code"}, TimeFormat: TimeFormatRFC3339})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := string(code)
+	for _, want := range []string{
+		"package orgapi",
+		"Code       string            `json:\"code\" gorm:\"primarykey\"`",
+		"Id int64 `json:\"id\"`",
+		"CreateTime time.Time         `json:\"createdAt\" gorm:\"column:created_at\"`",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("expected generated code to contain %s", want)
+		}
+	}
+	if strings.Contains(src, "sql_datetime") {
+		t.Error("rfc3339 time format should not use sql_datetime tag")
+	}
+}
+
+func Test_GenerateError(t *testing.T) {
+	cases := map[string]string{
+		`{"info": {}}`: "missing openapi or swagger version",
+		`{"openapi": "3.0.0", "paths": {"/org/{id}": {"get": {}}}}`: "path parameter id is not defined",
+		`{"openapi": "3.0.0", "paths": {"/org": {"post": {"requestBody": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Org"}}}}}}}}`: "$ref not found",
+	}
+	for spec, want := range cases {
+		_, err := Generate([]byte(spec), Options{})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error contains %q, got %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// writeEndpoint 输出接口对应的参数结构体和方法
+func (g *generator) writeEndpoint(buf *bytes.Buffer, ep *endpoint, imports map[string]bool) {
+	if ep.ParamsType != "" {
+		fmt.Fprintf(buf, "// %s %s 的参数\n", ep.ParamsType, ep.Name)
+		fmt.Fprintf(buf, "type %s struct {\n", ep.ParamsType)
+		for _, p := range ep.Params {
+			comment := p.Comment
+			if p.Required {
+				comment = strings.TrimSpace("必填 " + comment)
+			}
+			if comment != "" {
+				writeComment(buf, comment, "\t")
+			}
+			fmt.Fprintf(buf, "\t%s %s\n", p.GoName, p.Type)
+		}
+		buf.WriteString("}\n\n")
+	}
+	if ep.Row != "" {
+		g.writePaginated(buf, ep, imports)
+		return
+	}
+
+	args := []string{"ctx context.Context"}
+	if ep.ParamsType != "" {
+		args = append(args, "params "+ep.ParamsType)
+	}
+	if ep.Body != "" {
+		args = append(args, "body "+ep.Body)
+	}
+	result := g.resultType(ep.Result)
+
+	writeComment(buf, ep.Name+" "+ep.Comment, "")
+	if result == "" {
+		fmt.Fprintf(buf, "func (c *Client) %s(%s) error {\n", ep.Name, strings.Join(args, ", "))
+	} else {
+		fmt.Fprintf(buf, "func (c *Client) %s(%s) (%s, error) {\n", ep.Name, strings.Join(args, ", "), result)
+	}
+	fmt.Fprintf(buf, "\tr := c.R().Method(%q).Path(%s)\n", ep.Method, pathExpr(ep, imports))
+	writeParams(buf, ep, []string{"query", "header"}, imports, func(p param, value string) string {
+		if p.In == "header" {
+			return fmt.Sprintf("r.Header(%q, %s)", p.Name, stringExpr(p.Type, value, imports))
+		}
+		return fmt.Sprintf("r.Query(%q, %s)", p.Name, value)
+	})
+	if ep.Body != "" {
+		buf.WriteString("\tr.JSON(body)\n")
+	}
+	switch {
+	case result == "":
+		buf.WriteString("\t_, err := r.Do(ctx)\n\treturn err\n")
+	case strings.HasPrefix(result, "*"):
+		fmt.Fprintf(buf, "\tvar out %s\n", ep.Result)
+		buf.WriteString("\tif _, err := r.Into(&out).Do(ctx); err != nil {\n\t\treturn nil, err\n\t}\n\treturn &out, nil\n")
+	default:
+		fmt.Fprintf(buf, "\tvar out %s\n", ep.Result)
+		buf.WriteString("\t_, err := r.Into(&out).Do(ctx)\n\treturn out, err\n")
+	}
+	buf.WriteString("}\n\n")
+}
+
+// writePaginated 输出分页接口的 APIConfig、按页读取、Iterator 和 SyncToDB 方法
+func (g *generator) writePaginated(buf *bytes.Buffer, ep *endpoint, imports map[string]bool) {
+	paramsArg, paramsValue := "", ""
+	if ep.ParamsType != "" {
+		paramsArg, paramsValue = "params "+ep.ParamsType, "params"
+	}
+
+	fmt.Fprintf(buf, "// %sAPIConfig 返回 %s 的同步配置，可以用于 SyncToDB、SyncToFile 等\n", ep.Name, ep.Name)
+	fmt.Fprintf(buf, "func %sAPIConfig(%s) sdk.APIConfig {\n", ep.Name, paramsArg)
+	fmt.Fprintf(buf, "\tapi := sdk.APIConfig{APIPath: %s}\n", pathExpr(ep, imports))
+	writeParams(buf, ep, []string{"query"}, imports, func(p param, value string) string {
+		return fmt.Sprintf("api.AddParam(%q, %s)", p.Name, stringExpr(p.Type, value, imports))
+	})
+	buf.WriteString("\treturn api\n}\n\n")
+
+	args := []string{"ctx context.Context"}
+	if paramsArg != "" {
+		args = append(args, paramsArg)
+	}
+	writeComment(buf, ep.Name+" "+ep.Comment+"\n读取第 pageNum 页，页码从 1 开始", "")
+	fmt.Fprintf(buf, "func (c *Client) %s(%s, pageNum, pageSize int) (*sdk.Page[%s], error) {\n", ep.Name, strings.Join(args, ", "), ep.Row)
+	fmt.Fprintf(buf, "\tapi := %sAPIConfig(%s)\n", ep.Name, paramsValue)
+	fmt.Fprintf(buf, "\tr := c.R().Method(%q).Path(api.APIPath).Queries(api.Params()).Query(\"pageNum\", pageNum).Query(\"pageSize\", pageSize)\n", ep.Method)
+	writeParams(buf, ep, []string{"header"}, imports, func(p param, value string) string {
+		return fmt.Sprintf("r.Header(%q, %s)", p.Name, stringExpr(p.Type, value, imports))
+	})
+	fmt.Fprintf(buf, "\tvar page sdk.Page[%s]\n", ep.Row)
+	buf.WriteString("\tif _, err := r.Into(&page).Do(ctx); err != nil {\n\t\treturn nil, err\n\t}\n\treturn &page, nil\n}\n\n")
+
+	iterArgs := []string{}
+	callArgs := []string{"ctx"}
+	if paramsArg != "" {
+		iterArgs = append(iterArgs, paramsArg)
+		callArgs = append(callArgs, paramsValue)
+	}
+	iterArgs = append(iterArgs, "pageSize int")
+	callArgs = append(callArgs, "pageNum", "pageSize")
+	fmt.Fprintf(buf, "// %sIterator 逐条读取 %s 的全部数据\n", ep.Name, ep.Name)
+	fmt.Fprintf(buf, "func (c *Client) %sIterator(%s) *sdk.Iterator[%s] {\n", ep.Name, strings.Join(iterArgs, ", "), ep.Row)
+	fmt.Fprintf(buf, "\treturn sdk.NewIterator(func(ctx context.Context, pageNum int) ([]%s, error) {\n", ep.Row)
+	fmt.Fprintf(buf, "\t\tpage, err := c.%s(%s)\n", ep.Name, strings.Join(callArgs, ", "))
+	buf.WriteString("\t\tif err != nil {\n\t\t\treturn nil, err\n\t\t}\n\t\treturn page.Rows, nil\n\t})\n}\n\n")
+
+	// 只有结构体可以作为数据库的模型
+	if !g.isStruct(ep.Row) {
+		return
+	}
+	imports["gorm.io/gorm"] = true
+	syncArgs := []string{"db *gorm.DB"}
+	if paramsArg != "" {
+		syncArgs = append(syncArgs, paramsArg)
+	}
+	fmt.Fprintf(buf, "// Sync%sToDB 将 %s 的全部数据同步到数据库，表结构为 %s\n", ep.Name, ep.Name, ep.Row)
+	fmt.Fprintf(buf, "func (c *Client) Sync%sToDB(%s) (sdk.SyncResult, error) {\n", ep.Name, strings.Join(syncArgs, ", "))
+	fmt.Fprintf(buf, "\tvar rows []%s\n", ep.Row)
+	fmt.Fprintf(buf, "\treturn c.SyncToDB(db, %sAPIConfig(%s), &rows)\n}\n\n", ep.Name, paramsValue)
+}
+
+// resultType 返回值的类型，结构体返回指针
+func (g *generator) resultType(typ string) string {
+	if g.isStruct(typ) {
+		return "*" + typ
+	}
+	return typ
+}
+
+// writeParams 输出设置参数的语句，非必填的参数为零值时不设置
+func writeParams(buf *bytes.Buffer, ep *endpoint, in []string, imports map[string]bool, set func(p param, value string) string) {
+	for _, p := range ep.Params {
+		if !containsString(in, p.In) {
+			continue
+		}
+		value := "params." + p.GoName
+		switch {
+		case strings.HasPrefix(p.Type, "[]"):
+			fmt.Fprintf(buf, "\tfor _, v := range %s {\n\t\t%s\n\t}\n", value, set(param{Name: p.Name, In: p.In, Type: p.Type[2:]}, "v"))
+		case p.Required:
+			fmt.Fprintf(buf, "\t%s\n", set(p, value))
+		default:
+			fmt.Fprintf(buf, "\tif %s {\n\t\t%s\n\t}\n", notZero(p.Type, value), set(p, value))
+		}
+	}
+}
+
+func notZero(typ, value string) string {
+	switch typ {
+	case "string":
+		return value + ` != ""`
+	case "bool":
+		return value
+	}
+	return value + " != 0"
+}
+
+// stringExpr 将 value 转换为字符串的表达式
+func stringExpr(typ, value string, imports map[string]bool) string {
+	if typ == "string" {
+		return value
+	}
+	imports["fmt"] = true
+	return fmt.Sprintf("fmt.Sprint(%s)", value)
+}
+
+// pathExpr 拼接路径参数的表达式，例如 "/api/v1/organization/" + url.PathEscape(params.Id)
+func pathExpr(ep *endpoint, imports map[string]bool) string {
+	var parts []string
+	rest := ep.Path
+	for {
+		start := strings.Index(rest, "{")
+		end := strings.Index(rest, "}")
+		if start < 0 || end < start {
+			break
+		}
+		name := rest[start+1 : end]
+		if start > 0 {
+			parts = append(parts, fmt.Sprintf("%q", rest[:start]))
+		}
+		typ := "string"
+		for _, p := range ep.Params {
+			if p.In == "path" && p.Name == name {
+				typ = p.Type
+			}
+		}
+		imports["net/url"] = true
+		parts = append(parts, fmt.Sprintf("url.PathEscape(%s)", stringExpr(typ, "params."+GoName(name), imports)))
+		rest = rest[end+1:]
+	}
+	if rest != "" || len(parts) == 0 {
+		parts = append(parts, fmt.Sprintf("%q", rest))
+	}
+	return strings.Join(parts, " + ")
+}
@@ -0,0 +1,506 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// TimeFormatSQL date-time 字段按 sql datetime 格式解析，即 2006-01-02 15:04:05，ECNU 接口默认使用该格式
+	TimeFormatSQL = "sql_datetime"
+	// TimeFormatRFC3339 date-time 字段按 RFC3339 格式解析
+	TimeFormatRFC3339 = "rfc3339"
+)
+
+// Options 生成选项
+type Options struct {
+	// Package 生成代码的包名，默认 api
+	Package string
+	// Keys 作为主键的 json 字段名，默认 id，文档中的 x-primary-key 优先
+	Keys []string
+	// TimeFormat date-time 字段的格式，默认 sql_datetime
+	TimeFormat string
+}
+
+func (opts *Options) setDefault() {
+	if opts.Package == "" {
+		opts.Package = "api"
+	}
+	if opts.Keys == nil {
+		opts.Keys = []string{"id"}
+	}
+	if opts.TimeFormat == "" {
+		opts.TimeFormat = TimeFormatSQL
+	}
+}
+
+// param 生成方法的参数
+type param struct {
+	Name     string
+	GoName   string
+	In       string
+	Type     string
+	Required bool
+	Comment  string
+}
+
+// endpoint 生成方法的接口
+type endpoint struct {
+	Name    string
+	Comment string
+	Method  string
+	Path    string
+	Params  []param
+	// ParamsType 参数结构体的名称
+	ParamsType string
+	// Body 请求体的类型，为空时没有请求体
+	Body string
+	// Result 返回值的类型，为空时只返回 error
+	Result string
+	// Row 分页接口每一行的类型，为空时不是分页接口
+	Row string
+}
+
+type generator struct {
+	doc     *document
+	opts    Options
+	structs []Struct
+	// names 已经使用的类型名
+	names map[string]bool
+	// defined 已经生成的 schema 对应的结构体名
+	defined map[*schema]string
+	// endpoints 已经使用的方法名
+	endpoints map[string]bool
+}
+
+/*
+Generate 根据 OpenAPI 3 或 Swagger 2 的 json 文档生成 Go 代码
+
+生成的代码包括：
+
+  - 文档中用到的模型，带有 json、gorm 和 jsontime 的 tag，可以直接用于 SyncToDB
+  - Client 以及每个接口对应的方法，返回 APIResult 中解析后的 data
+  - 分页接口（data 中包含 rows、totalNum）额外生成 XxxAPIConfig、XxxIterator 和 SyncXxxToDB
+*/
+func Generate(spec []byte, opts Options) ([]byte, error) {
+	opts.setDefault()
+	doc, err := parseDocument(spec)
+	if err != nil {
+		return nil, err
+	}
+	g := &generator{doc: doc, opts: opts, names: make(map[string]bool), defined: make(map[*schema]string), endpoints: make(map[string]bool)}
+	// 文档中定义的模型优先使用原名
+	for _, item := range doc.schemas() {
+		g.names[GoName(item.Name)] = true
+	}
+
+	var endpoints []*endpoint
+	for _, p := range doc.Paths {
+		for _, o := range p.Item.Operations {
+			// Swagger 2 的 basePath 是接口路径的一部分
+			path := strings.TrimSuffix(doc.BasePath, "/") + p.Path
+			ep, err := g.endpoint(path, p.Item, o.Method, o.Operation)
+			if err != nil {
+				return nil, fmt.Errorf("generate %s %s fail: %v", o.Method, path, err)
+			}
+			endpoints = append(endpoints, ep)
+		}
+	}
+	return g.file(endpoints)
+}
+
+// uniqueName 返回没有使用过的类型名
+func (g *generator) uniqueName(name string) string {
+	res := name
+	for i := 2; g.names[res]; i++ {
+		res = fmt.Sprintf("%s%d", name, i)
+	}
+	g.names[res] = true
+	return res
+}
+
+// uniqueEndpointName 返回没有使用过的方法名
+func (g *generator) uniqueEndpointName(name string) string {
+	res := name
+	for i := 2; g.endpoints[res]; i++ {
+		res = fmt.Sprintf("%s%d", name, i)
+	}
+	g.endpoints[res] = true
+	return res
+}
+
+// merge 合并 allOf，返回对象 schema
+func (g *generator) merge(s *schema) (*schema, error) {
+	s, err := g.doc.resolve(s)
+	if err != nil || s == nil || len(s.AllOf) == 0 {
+		return s, err
+	}
+	merged := &schema{Type: "object", Title: s.Title, Description: s.Description, Properties: s.Properties, PrimaryKey: s.PrimaryKey}
+	for _, part := range s.AllOf {
+		part, err := g.merge(part)
+		if err != nil {
+			return nil, err
+		}
+		merged.Properties = append(merged.Properties, part.Properties...)
+		if merged.Description == "" {
+			merged.Description = part.Description
+		}
+	}
+	return merged, nil
+}
+
+// goType 返回 schema 对应的 Go 类型，name 为需要生成结构体时使用的名称
+func (g *generator) goType(s *schema, name string) (string, error) {
+	if s == nil {
+		return "interface{}", nil
+	}
+	if s.Ref != "" {
+		name = GoName(refName(s.Ref))
+	}
+	// key 为合并 allOf 前的 schema，保证同一个模型只生成一次
+	key, err := g.doc.resolve(s)
+	if err != nil {
+		return "", err
+	}
+	s, err = g.merge(key)
+	if err != nil {
+		return "", err
+	}
+	switch s.Type {
+	case "string":
+		if s.Format == "date-time" {
+			return "time.Time", nil
+		}
+		return "string", nil
+	case "integer":
+		if s.Format == "int64" {
+			return "int64", nil
+		}
+		return "int", nil
+	case "number":
+		if s.Format == "float" {
+			return "float32", nil
+		}
+		return "float64", nil
+	case "boolean":
+		return "bool", nil
+	case "array":
+		item, err := g.goType(s.Items, name+"Item")
+		if err != nil {
+			return "", err
+		}
+		return "[]" + item, nil
+	case "object", "":
+		if len(s.Properties) > 0 {
+			return g.defineStruct(key, s, name)
+		}
+		if len(s.AdditionalProperties) > 0 && s.AdditionalProperties[0] == '{' {
+			var value schema
+			if err := json.Unmarshal(s.AdditionalProperties, &value); err != nil {
+				return "", err
+			}
+			valueType, err := g.goType(&value, name+"Value")
+			if err != nil {
+				return "", err
+			}
+			return "map[string]" + valueType, nil
+		}
+		if s.Type == "object" {
+			return "map[string]interface{}", nil
+		}
+	}
+	return "interface{}", nil
+}
+
+// defineStruct 生成结构体，同一个 schema 只生成一次，文档中定义的模型使用原名，其余的避免重名
+func (g *generator) defineStruct(key, s *schema, name string) (string, error) {
+	if defined, ok := g.defined[key]; ok {
+		return defined, nil
+	}
+	if g.schemaByGoName(name) != key {
+		name = g.uniqueName(name)
+	}
+	g.defined[key] = name
+
+	comment := s.Description
+	if comment == "" {
+		comment = s.Title
+	}
+	index := len(g.structs)
+	g.structs = append(g.structs, Struct{Name: name, Comment: comment})
+
+	// 显式标记了主键时，只使用标记的字段
+	explicitKey := false
+	for _, prop := range s.Properties {
+		if prop.Schema.PrimaryKey {
+			explicitKey = true
+		}
+	}
+	var fields []Field
+	for _, prop := range s.Properties {
+		typ, err := g.goType(prop.Schema, name+GoName(prop.Name))
+		if err != nil {
+			return "", fmt.Errorf("property %s.%s: %v", name, prop.Name, err)
+		}
+		f := Field{JSONName: prop.Name, Type: typ, Comment: prop.Schema.Description}
+		if explicitKey {
+			f.PrimaryKey = prop.Schema.PrimaryKey
+		} else {
+			f.PrimaryKey = containsString(g.opts.Keys, prop.Name)
+		}
+		switch {
+		case typ == "time.Time":
+			f.Datetime = g.opts.TimeFormat == TimeFormatSQL
+		case strings.HasPrefix(typ, "[]"), strings.HasPrefix(typ, "map["), g.isStruct(typ), typ == "interface{}":
+			f.Serializer = true
+		}
+		fields = append(fields, f)
+	}
+	g.structs[index].Fields = fields
+	return name, nil
+}
+
+// schemaByGoName 返回文档中名称转换后为 name 的模型
+func (g *generator) schemaByGoName(name string) *schema {
+	for _, item := range g.doc.schemas() {
+		if GoName(item.Name) == name {
+			return item.Schema
+		}
+	}
+	return nil
+}
+
+func (g *generator) isStruct(typ string) bool {
+	for _, s := range g.structs {
+		if s.Name == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// endpoint 解析一个接口
+func (g *generator) endpoint(path string, item *pathItem, method string, op *operation) (*endpoint, error) {
+	ep := &endpoint{Method: method, Path: path}
+	if op.OperationId != "" {
+		ep.Name = g.uniqueEndpointName(GoName(op.OperationId))
+	} else {
+		ep.Name = g.uniqueEndpointName(endpointName(method, path))
+	}
+	ep.Comment = strings.TrimSpace(op.Summary)
+	if ep.Comment == "" {
+		ep.Comment = strings.TrimSpace(op.Description)
+	}
+	if ep.Comment == "" {
+		ep.Comment = fmt.Sprintf("调用 %s %s", method, path)
+	}
+
+	// 参数，接口上的参数覆盖路径上的同名参数
+	var params []*parameter
+	for _, p := range append(append([]*parameter(nil), item.Parameters...), op.Parameters...) {
+		p, err := g.doc.resolveParameter(p)
+		if err != nil {
+			return nil, err
+		}
+		for i, exist := range params {
+			if exist.Name == p.Name && exist.In == p.In {
+				params = append(params[:i], params[i+1:]...)
+				break
+			}
+		}
+		params = append(params, p)
+	}
+	var err error
+	for _, p := range params {
+		switch p.In {
+		case "body":
+			if ep.Body, err = g.goType(p.Schema, ep.Name+"Body"); err != nil {
+				return nil, err
+			}
+		case "path", "query", "header":
+			typ, err := g.paramType(p)
+			if err != nil {
+				return nil, err
+			}
+			ep.Params = append(ep.Params, param{
+				Name: p.Name, GoName: GoName(p.Name), In: p.In, Type: typ,
+				Required: p.Required || p.In == "path", Comment: strings.TrimSpace(p.Description),
+			})
+		}
+	}
+	if op.RequestBody != nil {
+		if ep.Body, err = g.goType(jsonSchema(op.RequestBody.Content), ep.Name+"Body"); err != nil {
+			return nil, err
+		}
+	}
+
+	// 响应，APIResult 中的 data 为实际的返回值
+	res, err := g.doc.successResponse(op)
+	if err != nil {
+		return nil, err
+	}
+	data, err := g.data(res)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := g.rows(data)
+	if err != nil {
+		return nil, err
+	}
+	// 只有不带请求体的 GET 接口可以使用 APIConfig 同步
+	paginated := rows != nil && method == "GET" && ep.Body == ""
+	if op.Paginated != nil {
+		paginated = paginated && *op.Paginated
+	}
+	if paginated {
+		if ep.Row, err = g.goType(rows, ep.Name+"Row"); err != nil {
+			return nil, err
+		}
+		// 分页参数由生成的方法处理
+		var filtered []param
+		for _, p := range ep.Params {
+			if p.In != "query" || (p.Name != "pageNum" && p.Name != "pageSize") {
+				filtered = append(filtered, p)
+			}
+		}
+		ep.Params = filtered
+	} else if data != nil {
+		if ep.Result, err = g.goType(data, ep.Name+"Result"); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range pathParams(path) {
+		found := false
+		for _, p := range ep.Params {
+			found = found || (p.In == "path" && p.Name == name)
+		}
+		if !found {
+			return nil, fmt.Errorf("path parameter %s is not defined", name)
+		}
+	}
+	if len(ep.Params) > 0 {
+		ep.ParamsType = g.uniqueName(ep.Name + "Params")
+	}
+	return ep, nil
+}
+
+// data 如果响应是 APIResult，返回其中 data 的 schema
+func (g *generator) data(res *schema) (*schema, error) {
+	merged, err := g.merge(res)
+	if err != nil || merged == nil {
+		return nil, err
+	}
+	// 没有 data 的 APIResult 只需要判断 errCode
+	if merged.Properties.get("errCode") != nil {
+		return merged.Properties.get("data"), nil
+	}
+	return res, nil
+}
+
+// rows 如果 data 是分页数据，返回 rows 中每一行的 schema
+func (g *generator) rows(data *schema) (*schema, error) {
+	merged, err := g.merge(data)
+	if err != nil || merged == nil {
+		return nil, err
+	}
+	if merged.Properties.get("totalNum") == nil {
+		return nil, nil
+	}
+	rows, err := g.merge(merged.Properties.get("rows"))
+	if err != nil || rows == nil || rows.Type != "array" {
+		return nil, err
+	}
+	return rows.Items, nil
+}
+
+// paramType 参数的 Go 类型，时间参数保持字符串，由调用方决定格式
+func (g *generator) paramType(p *parameter) (string, error) {
+	s := p.Schema
+	if s == nil {
+		s = &schema{Type: p.Type, Format: p.Format}
+	}
+	s, err := g.merge(s)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case s.Type == "string":
+		return "string", nil
+	case s.Type == "array":
+		item, err := g.paramType(&parameter{Schema: s.Items})
+		if err != nil {
+			return "", err
+		}
+		return "[]" + item, nil
+	case s.Type == "integer" || s.Type == "number" || s.Type == "boolean":
+		return g.goType(s, "")
+	}
+	return "string", nil
+}
+
+// endpointName 没有 operationId 时根据方法和路径生成方法名，例如 GET /api/v1/organization/{id} -> GetApiV1OrganizationById
+func endpointName(method, path string) string {
+	var sb strings.Builder
+	sb.WriteString(GoName(strings.ToLower(method)))
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			sb.WriteString("By" + GoName(strings.Trim(segment, "{}")))
+		} else if segment != "" {
+			sb.WriteString(GoName(segment))
+		}
+	}
+	return sb.String()
+}
+
+// pathParams 返回路径中的参数名，例如 /api/v1/organization/{id} -> id
+func pathParams(path string) []string {
+	var names []string
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			names = append(names, strings.Trim(segment, "{}"))
+		}
+	}
+	return names
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// file 输出完整的 Go 文件
+func (g *generator) file(endpoints []*endpoint) ([]byte, error) {
+	var body bytes.Buffer
+	title := g.doc.Info.Title
+	if title == "" {
+		title = "OpenAPI"
+	}
+	fmt.Fprintf(&body, "// Client %s 的接口，由 ecnu-gen 生成\n", title)
+	body.WriteString("type Client struct {\n\t*sdk.OAuth2Client\n}\n\n")
+	body.WriteString("// NewClient 创建 Client，c 为 nil 时使用 sdk.GetOpenAPIClient()\n")
+	body.WriteString("func NewClient(c *sdk.OAuth2Client) *Client {\n\tif c == nil {\n\t\tc = sdk.GetOpenAPIClient()\n\t}\n\treturn &Client{OAuth2Client: c}\n}\n\n")
+
+	imports := map[string]bool{"context": true, "github.com/ecnu/ecnu-openapi-sdk-go/sdk": true}
+	for _, ep := range endpoints {
+		g.writeEndpoint(&body, ep, imports)
+	}
+	for _, s := range g.structs {
+		s.write(&body)
+	}
+	if usesTime(g.structs) {
+		imports["time"] = true
+	}
+	list := make([]string, 0, len(imports))
+	for imp := range imports {
+		list = append(list, imp)
+	}
+	sort.Strings(list)
+	return formatFile(generatedHeader, g.opts.Package, list, body.Bytes())
+}
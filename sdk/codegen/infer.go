@@ -0,0 +1,208 @@
+package codegen
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk"
+)
+
+// SQLDatetimeLayout ECNU 接口中日期时间字符串的格式，对应 jsontime 的 sql_datetime
+const SQLDatetimeLayout = "2006-01-02 15:04:05"
+
+// DefaultSampleSize 推断模型时默认读取的样例行数
+const DefaultSampleSize = 100
+
+// ModelOptions 根据样例数据生成模型的选项
+type ModelOptions struct {
+	// Package 生成代码的包名，默认 model
+	Package string
+	// Name 结构体名称，默认根据接口路径的最后一段生成
+	Name string
+	// Keys 作为主键的 json 字段名，默认 id
+	Keys []string
+	// SampleSize 读取的样例行数，默认 100，只读取第一页
+	SampleSize int
+}
+
+func (opts *ModelOptions) setDefault(apiPath string) {
+	if opts.Package == "" {
+		opts.Package = "model"
+	}
+	if opts.Name == "" {
+		u := strings.SplitN(apiPath, "?", 2)[0]
+		opts.Name = GoName(path.Base(u))
+	}
+	if opts.Keys == nil {
+		opts.Keys = []string{"id"}
+	}
+	if opts.SampleSize <= 0 {
+		opts.SampleSize = DefaultSampleSize
+	}
+}
+
+/*
+GenerateModel 调用 GetRows 读取接口第一页的样例数据，推断字段类型后输出模型的 Go 源文件
+
+样例数据只能反映已有的值，输出的文件不带 "Code generated ... DO NOT EDIT" 头部，生成后请确认并按需修改：
+
+  - 样例中全部为整数的数值字段会生成 int，如果接口实际会返回小数，需要改为 float64
+  - 样例中全部为 null 的字段会生成 string
+*/
+func GenerateModel(r sdk.Requester, apiPath string, opts ModelOptions) ([]byte, error) {
+	opts.setDefault(apiPath)
+	res, err := r.GetRows(apiPath, 1, opts.SampleSize)
+	if err != nil {
+		return nil, fmt.Errorf("get sample rows fail: %v", err)
+	}
+	s, err := InferStruct(opts.Name, res.Rows, opts.Keys)
+	if err != nil {
+		return nil, err
+	}
+	s.Comment = fmt.Sprintf("根据 %s 的样例数据生成", apiPath)
+	return formatStructs("", opts.Package, []Struct{s})
+}
+
+// InferStruct 根据样例数据推断结构体，keys 为主键的 json 字段名，字段按主键在前、其余按名称排序
+func InferStruct(name string, rows []interface{}, keys []string) (Struct, error) {
+	s := Struct{Name: name}
+	if len(rows) == 0 {
+		return s, fmt.Errorf("no sample rows, can not infer model")
+	}
+	samples := make(map[string][]interface{})
+	for i, row := range rows {
+		values, ok := row.(map[string]interface{})
+		if !ok {
+			return s, fmt.Errorf("row %d is not an object", i)
+		}
+		for key, value := range values {
+			samples[key] = append(samples[key], value)
+		}
+	}
+	for _, key := range keys {
+		if _, ok := samples[key]; !ok {
+			return s, fmt.Errorf("key %s not found in sample rows", key)
+		}
+	}
+
+	names := make([]string, 0, len(samples))
+	for key := range samples {
+		names = append(names, key)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ki, kj := containsString(keys, names[i]), containsString(keys, names[j])
+		if ki != kj {
+			return ki
+		}
+		return names[i] < names[j]
+	})
+	for _, key := range names {
+		f := inferField(samples[key])
+		f.JSONName = key
+		f.PrimaryKey = containsString(keys, key)
+		s.Fields = append(s.Fields, f)
+	}
+	return s, nil
+}
+
+// inferField 根据一列的样例值推断字段类型，null 不参与推断
+func inferField(values []interface{}) Field {
+	var kinds []string
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		if kind := valueKind(v); !containsString(kinds, kind) {
+			kinds = append(kinds, kind)
+		}
+	}
+	// 整数和小数混合时使用 float64
+	if len(kinds) == 2 && containsString(kinds, "int") && containsString(kinds, "float64") {
+		kinds = []string{"float64"}
+	}
+	// 时间和普通字符串（例如空字符串）混合时，无法解析为 time.Time，保持字符串
+	if len(kinds) > 1 && onlyStrings(kinds) {
+		kinds = []string{"string"}
+	}
+	switch {
+	case len(kinds) == 0:
+		return Field{Type: "string", Comment: "样例数据中均为 null，请确认类型"}
+	case len(kinds) > 1:
+		return Field{Type: "interface{}", Serializer: true, Comment: "样例数据中的类型不一致：" + strings.Join(kinds, "、")}
+	}
+	switch kind := kinds[0]; kind {
+	case "sql_datetime":
+		return Field{Type: "time.Time", Datetime: true}
+	case "rfc3339":
+		return Field{Type: "time.Time"}
+	case "array", "object":
+		return Field{Type: containerType(kind, values), Serializer: true}
+	default:
+		return Field{Type: kind}
+	}
+}
+
+func onlyStrings(kinds []string) bool {
+	for _, kind := range kinds {
+		if kind != "string" && kind != "sql_datetime" && kind != "rfc3339" {
+			return false
+		}
+	}
+	return true
+}
+
+func valueKind(v interface{}) string {
+	switch v := v.(type) {
+	case bool:
+		return "bool"
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return "int"
+		}
+		return "float64"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "int"
+		}
+		return "float64"
+	case string:
+		if _, err := time.Parse(SQLDatetimeLayout, v); err == nil {
+			return "sql_datetime"
+		}
+		if _, err := time.Parse(time.RFC3339, v); err == nil {
+			return "rfc3339"
+		}
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// containerType 数组元素类型一致时使用具体的类型，对象统一为 map
+func containerType(kind string, values []interface{}) string {
+	if kind == "object" {
+		return "map[string]interface{}"
+	}
+	var elems []interface{}
+	for _, v := range values {
+		if items, ok := v.([]interface{}); ok {
+			elems = append(elems, items...)
+		}
+	}
+	elem := inferField(elems)
+	switch elem.Type {
+	case "string", "int", "float64", "bool":
+		if len(elems) > 0 {
+			return "[]" + elem.Type
+		}
+	}
+	return "[]interface{}"
+}
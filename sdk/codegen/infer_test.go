@@ -0,0 +1,120 @@
+package codegen
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func Test_GenerateModel(t *testing.T) {
+	mc := sdk.NewMemoryClient()
+	mc.AddRows("/api/v1/sync/fakewithts", []interface{}{
+		map[string]interface{}{
+			"id": float64(1), "created_at": "2023-01-01 08:00:00", "updated_at": "2023-01-02 08:00:00",
+			"deleted_mark": float64(0), "userId": "10001", "name": "张三", "score": float64(90),
+			"tags": []interface{}{"a"}, "remark": nil, "birthday": "",
+		},
+		map[string]interface{}{
+			"id": float64(2), "created_at": "2023-01-01 09:00:00", "updated_at": "2023-01-03 08:00:00",
+			"deleted_mark": float64(1), "userId": "10002", "name": "李四", "score": 85.5,
+			"tags": []interface{}{}, "remark": nil, "birthday": "2000-01-01 00:00:00",
+		},
+	})
+	code, err := GenerateModel(mc, "/api/v1/sync/fakewithts?ts=0", ModelOptions{Name: "FakeRowsWithTS"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 注释会打断 gofmt 的对齐，比较时忽略空白的数量
+	src := strings.Join(strings.Fields(string(code)), " ")
+	for _, line := range []string{
+		"package model",
+		"// FakeRowsWithTS 根据 /api/v1/sync/fakewithts?ts=0 的样例数据生成",
+		"Id          int       `json:\"id\" gorm:\"primarykey;autoIncrement:false\"`",
+		"Birthday    string    `json:\"birthday\"`",
+		"CreateTime  time.Time `json:\"created_at\" time_format:\"sql_datetime\" time_location:\"shanghai\" gorm:\"column:created_at\"`",
+		"UpdateTime  time.Time `json:\"updated_at\" time_format:\"sql_datetime\" time_location:\"shanghai\" gorm:\"index;column:updated_at\"`",
+		"DeletedMark int       `json:\"deleted_mark\"`",
+		"Score       float64   `json:\"score\"`",
+		"Tags        []string  `json:\"tags\" gorm:\"serializer:json\"`",
+		"// 样例数据中均为 null，请确认类型\n\tRemark string `json:\"remark\"`",
+		"UserId      string    `json:\"userId\"`",
+	} {
+		if line = strings.Join(strings.Fields(line), " "); !strings.Contains(src, line) {
+			t.Errorf("expected generated model to contain %s\n%s", line, src)
+		}
+	}
+	// 生成的模型需要人工确认，不是不可修改的生成代码
+	if !strings.HasPrefix(src, "package model") || strings.Contains(src, "DO NOT EDIT") {
+		t.Errorf("model should not have generated header:\n%s", src)
+	}
+	// 主键在最前面
+	if strings.Index(src, "Id ") > strings.Index(src, "Birthday") {
+		t.Errorf("expected key field first:\n%s", src)
+	}
+}
+
+func Test_InferStructError(t *testing.T) {
+	if _, err := InferStruct("Empty", nil, []string{"id"}); err == nil {
+		t.Error("expected error for empty rows")
+	}
+	rows := []interface{}{map[string]interface{}{"code": "0445"}}
+	if _, err := InferStruct("Org", rows, []string{"id"}); err == nil || !strings.Contains(err.Error(), "key id not found") {
+		t.Errorf("expected key not found error, got %v", err)
+	}
+	s, err := InferStruct("Org", rows, []string{"code"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Fields[0].PrimaryKey || s.Fields[0].Tag() != `json:"code" gorm:"primarykey"` {
+		t.Errorf("unexpected key field: %+v", s.Fields[0])
+	}
+}
+
+func Test_StructType(t *testing.T) {
+	rows := []interface{}{
+		map[string]interface{}{"id": float64(1), "updated_at": "2023-01-02 08:00:00", "name": "张三", "tags": []interface{}{"a"}},
+		map[string]interface{}{"id": float64(2), "updated_at": "2023-01-03 08:00:00", "name": "李四", "tags": []interface{}{}},
+	}
+	s, err := InferStruct("Row", rows, []string{"id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	typ, err := s.Type()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if field, _ := typ.FieldByName("UpdateTime"); field.Tag.Get("gorm") != "index;column:updated_at" {
+		t.Errorf("unexpected tag: %s", field.Tag)
+	}
+
+	mc := sdk.NewMemoryClient()
+	mc.AddRows("/api/v1/rows", rows)
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := reflect.New(reflect.SliceOf(typ)).Interface()
+	res, err := mc.SyncToDB(db.Table("rows"), sdk.APIConfig{APIPath: "/api/v1/rows"}, model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 再同步一次，主键相同的数据会被更新而不是重复写入
+	if _, err := mc.SyncToDB(db.Table("rows"), sdk.APIConfig{APIPath: "/api/v1/rows"}, model); err != nil {
+		t.Fatal(err)
+	}
+	var total int64
+	var name string
+	db.Table("rows").Count(&total)
+	db.Table("rows").Where("updated_at > ?", "2023-01-03").Select("name").Scan(&name)
+	if res.Rows != 2 || total != 2 || name != "李四" {
+		t.Errorf("unexpected sync result: %d %d %s", res.Rows, total, name)
+	}
+}
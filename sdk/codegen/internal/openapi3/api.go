@@ -0,0 +1,143 @@
+// Code generated by ecnu-gen. DO NOT EDIT.
+
+package openapi3
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk"
+	"gorm.io/gorm"
+)
+
+// Client ECNU OpenAPI 示例 的接口，由 ecnu-gen 生成
+type Client struct {
+	*sdk.OAuth2Client
+}
+
+// NewClient 创建 Client，c 为 nil 时使用 sdk.GetOpenAPIClient()
+func NewClient(c *sdk.OAuth2Client) *Client {
+	if c == nil {
+		c = sdk.GetOpenAPIClient()
+	}
+	return &Client{OAuth2Client: c}
+}
+
+// ListFakeWithTSParams ListFakeWithTS 的参数
+type ListFakeWithTSParams struct {
+	// 只返回更新时间大于 ts 的数据
+	Ts int64
+}
+
+// ListFakeWithTSAPIConfig 返回 ListFakeWithTS 的同步配置，可以用于 SyncToDB、SyncToFile 等
+func ListFakeWithTSAPIConfig(params ListFakeWithTSParams) sdk.APIConfig {
+	api := sdk.APIConfig{APIPath: "/api/v1/sync/fakewithts"}
+	if params.Ts != 0 {
+		api.AddParam("ts", fmt.Sprint(params.Ts))
+	}
+	return api
+}
+
+// ListFakeWithTS 增量同步测试接口
+// 读取第 pageNum 页，页码从 1 开始
+func (c *Client) ListFakeWithTS(ctx context.Context, params ListFakeWithTSParams, pageNum, pageSize int) (*sdk.Page[FakeRowWithTS], error) {
+	api := ListFakeWithTSAPIConfig(params)
+	r := c.R().Method("GET").Path(api.APIPath).Queries(api.Params()).Query("pageNum", pageNum).Query("pageSize", pageSize)
+	var page sdk.Page[FakeRowWithTS]
+	if _, err := r.Into(&page).Do(ctx); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// ListFakeWithTSIterator 逐条读取 ListFakeWithTS 的全部数据
+func (c *Client) ListFakeWithTSIterator(params ListFakeWithTSParams, pageSize int) *sdk.Iterator[FakeRowWithTS] {
+	return sdk.NewIterator(func(ctx context.Context, pageNum int) ([]FakeRowWithTS, error) {
+		page, err := c.ListFakeWithTS(ctx, params, pageNum, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		return page.Rows, nil
+	})
+}
+
+// SyncListFakeWithTSToDB 将 ListFakeWithTS 的全部数据同步到数据库，表结构为 FakeRowWithTS
+func (c *Client) SyncListFakeWithTSToDB(db *gorm.DB, params ListFakeWithTSParams) (sdk.SyncResult, error) {
+	var rows []FakeRowWithTS
+	return c.SyncToDB(db, ListFakeWithTSAPIConfig(params), &rows)
+}
+
+// CreateOrganization 新建组织机构
+func (c *Client) CreateOrganization(ctx context.Context, body Organization) (*CreateOrganizationResult, error) {
+	r := c.R().Method("POST").Path("/api/v1/organization")
+	r.JSON(body)
+	var out CreateOrganizationResult
+	if _, err := r.Into(&out).Do(ctx); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetOrganizationParams GetOrganization 的参数
+type GetOrganizationParams struct {
+	// 必填
+	Id int64
+}
+
+// GetOrganization 获取组织机构详情
+func (c *Client) GetOrganization(ctx context.Context, params GetOrganizationParams) (*Organization, error) {
+	r := c.R().Method("GET").Path("/api/v1/organization/" + url.PathEscape(fmt.Sprint(params.Id)))
+	var out Organization
+	if _, err := r.Into(&out).Do(ctx); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteOrganizationParams DeleteOrganization 的参数
+type DeleteOrganizationParams struct {
+	// 必填
+	Id int64
+}
+
+// DeleteOrganization 删除组织机构
+func (c *Client) DeleteOrganization(ctx context.Context, params DeleteOrganizationParams) error {
+	r := c.R().Method("DELETE").Path("/api/v1/organization/" + url.PathEscape(fmt.Sprint(params.Id)))
+	_, err := r.Do(ctx)
+	return err
+}
+
+type FakeRowWithTS struct {
+	Id          int       `json:"id" gorm:"primarykey;autoIncrement:false"`
+	CreateTime  time.Time `json:"created_at" time_format:"sql_datetime" time_location:"shanghai" gorm:"column:created_at"`
+	UpdateTime  time.Time `json:"updated_at" time_format:"sql_datetime" time_location:"shanghai" gorm:"index;column:updated_at"`
+	DeletedMark int       `json:"deleted_mark"`
+	// 工号
+	UserId string `json:"userId"`
+	// 姓名
+	Name string `json:"name"`
+}
+
+// Organization 组织机构
+type Organization struct {
+	Id int64 `json:"id" gorm:"primarykey;autoIncrement:false"`
+	// 单位代码
+	Code       string            `json:"code"`
+	Name       string            `json:"name"`
+	ParentId   int64             `json:"parentId"`
+	Manager    Person            `json:"manager" gorm:"serializer:json"`
+	Tags       []string          `json:"tags" gorm:"serializer:json"`
+	Extra      map[string]string `json:"extra" gorm:"serializer:json"`
+	CreateTime time.Time         `json:"createdAt" time_format:"sql_datetime" time_location:"shanghai" gorm:"column:created_at"`
+}
+
+type Person struct {
+	UserId string `json:"userId" gorm:"primarykey"`
+	Name   string `json:"name"`
+}
+
+type CreateOrganizationResult struct {
+	Id int64 `json:"id" gorm:"primarykey;autoIncrement:false"`
+}
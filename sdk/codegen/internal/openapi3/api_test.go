@@ -0,0 +1,120 @@
+package openapi3
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk"
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk/sdktest"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	testRowsPath = "/api/v1/sync/fakewithts"
+	testOrgPath  = "/api/v1/organization/1"
+)
+
+func newTestClient(t *testing.T) (*Client, *sdktest.Server) {
+	t.Helper()
+	srv := sdktest.NewServer()
+	t.Cleanup(srv.Close)
+	err := sdk.SetupOAuth2ClientCredentials(sdk.OAuth2Config{
+		ClientId:     srv.ClientId,
+		ClientSecret: srv.ClientSecret,
+		BaseUrl:      srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []map[string]interface{}
+	for i := 1; i <= 25; i++ {
+		updatedAt := "2023-01-01 00:00:00"
+		if i > 20 {
+			updatedAt = "2023-06-01 00:00:00"
+		}
+		rows = append(rows, map[string]interface{}{
+			"id": i, "created_at": "2022-12-31 08:00:00", "updated_at": updatedAt,
+			"deleted_mark": 0, "userId": fmt.Sprintf("u%03d", i), "name": fmt.Sprintf("用户%d", i),
+		})
+	}
+	if _, err := srv.AddRows(testRowsPath, rows); err != nil {
+		t.Fatal(err)
+	}
+	return NewClient(nil), srv
+}
+
+func Test_GeneratedClient(t *testing.T) {
+	c, srv := newTestClient(t)
+	ctx := context.Background()
+	srv.AddData(testOrgPath, map[string]interface{}{
+		"id": 1, "code": "0445", "name": "华东师范大学",
+		"manager":   map[string]string{"userId": "10000", "name": "校长"},
+		"tags":      []string{"985", "211"},
+		"createdAt": "1951-10-16 00:00:00",
+	})
+
+	org, err := c.GetOrganization(ctx, GetOrganizationParams{Id: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc, _ := time.LoadLocation("Asia/Shanghai")
+	if org.Code != "0445" || org.Manager.UserId != "10000" || len(org.Tags) != 2 ||
+		!org.CreateTime.Equal(time.Date(1951, 10, 16, 0, 0, 0, 0, loc)) {
+		t.Errorf("unexpected organization: %+v", org)
+	}
+	if err := c.DeleteOrganization(ctx, DeleteOrganizationParams{Id: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if srv.Requests(testOrgPath) != 2 {
+		t.Errorf("expected 2 requests, got %d", srv.Requests(testOrgPath))
+	}
+
+	page, err := c.ListFakeWithTS(ctx, ListFakeWithTSParams{}, 3, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.TotalNum != 25 || len(page.Rows) != 5 || page.Rows[0].Id != 21 {
+		t.Errorf("unexpected page: %d %d", page.TotalNum, len(page.Rows))
+	}
+
+	// ts 参数只返回之后更新的数据
+	ts := time.Date(2023, 3, 1, 0, 0, 0, 0, loc).Unix()
+	rows, err := c.ListFakeWithTSIterator(ListFakeWithTSParams{Ts: ts}, 2).All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 5 || rows[0].UserId != "u021" || rows[0].UpdateTime.Month() != time.June {
+		t.Errorf("unexpected rows: %+v", rows)
+	}
+}
+
+func Test_GeneratedSyncToDB(t *testing.T) {
+	c, _ := newTestClient(t)
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.SyncListFakeWithTSToDB(db, ListFakeWithTSParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total int64
+	db.Model(&FakeRowWithTS{}).Count(&total)
+	if res.Rows != 25 || total != 25 {
+		t.Errorf("expected 25 rows, got %d %d", res.Rows, total)
+	}
+	var row FakeRowWithTS
+	if err := db.First(&row, "updated_at > ?", "2023-03-01").Error; err != nil {
+		t.Fatal(err)
+	}
+	if row.Id != 21 {
+		t.Errorf("expected row 21, got %d", row.Id)
+	}
+}
@@ -0,0 +1,114 @@
+// Code generated by ecnu-gen. DO NOT EDIT.
+
+package swagger2
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk"
+	"gorm.io/gorm"
+)
+
+// Client 学生信息 的接口，由 ecnu-gen 生成
+type Client struct {
+	*sdk.OAuth2Client
+}
+
+// NewClient 创建 Client，c 为 nil 时使用 sdk.GetOpenAPIClient()
+func NewClient(c *sdk.OAuth2Client) *Client {
+	if c == nil {
+		c = sdk.GetOpenAPIClient()
+	}
+	return &Client{OAuth2Client: c}
+}
+
+// GetApiV1StudentListParams GetApiV1StudentList 的参数
+type GetApiV1StudentListParams struct {
+	// 年级
+	Grade   string
+	XTenant string
+}
+
+// GetApiV1StudentListAPIConfig 返回 GetApiV1StudentList 的同步配置，可以用于 SyncToDB、SyncToFile 等
+func GetApiV1StudentListAPIConfig(params GetApiV1StudentListParams) sdk.APIConfig {
+	api := sdk.APIConfig{APIPath: "/api/v1/student/list"}
+	if params.Grade != "" {
+		api.AddParam("grade", params.Grade)
+	}
+	return api
+}
+
+// GetApiV1StudentList 学生列表
+// 读取第 pageNum 页，页码从 1 开始
+func (c *Client) GetApiV1StudentList(ctx context.Context, params GetApiV1StudentListParams, pageNum, pageSize int) (*sdk.Page[StudentInfo], error) {
+	api := GetApiV1StudentListAPIConfig(params)
+	r := c.R().Method("GET").Path(api.APIPath).Queries(api.Params()).Query("pageNum", pageNum).Query("pageSize", pageSize)
+	if params.XTenant != "" {
+		r.Header("X-Tenant", params.XTenant)
+	}
+	var page sdk.Page[StudentInfo]
+	if _, err := r.Into(&page).Do(ctx); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// GetApiV1StudentListIterator 逐条读取 GetApiV1StudentList 的全部数据
+func (c *Client) GetApiV1StudentListIterator(params GetApiV1StudentListParams, pageSize int) *sdk.Iterator[StudentInfo] {
+	return sdk.NewIterator(func(ctx context.Context, pageNum int) ([]StudentInfo, error) {
+		page, err := c.GetApiV1StudentList(ctx, params, pageNum, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		return page.Rows, nil
+	})
+}
+
+// SyncGetApiV1StudentListToDB 将 GetApiV1StudentList 的全部数据同步到数据库，表结构为 StudentInfo
+func (c *Client) SyncGetApiV1StudentListToDB(db *gorm.DB, params GetApiV1StudentListParams) (sdk.SyncResult, error) {
+	var rows []StudentInfo
+	return c.SyncToDB(db, GetApiV1StudentListAPIConfig(params), &rows)
+}
+
+// SearchStudentsParams SearchStudents 的参数
+type SearchStudentsParams struct {
+	// 必填
+	Ids []string
+}
+
+// SearchStudents 按学号查询学生
+func (c *Client) SearchStudents(ctx context.Context, params SearchStudentsParams) ([]StudentInfo, error) {
+	r := c.R().Method("GET").Path("/api/v1/student/search")
+	for _, v := range params.Ids {
+		r.Query("ids", v)
+	}
+	var out []StudentInfo
+	_, err := r.Into(&out).Do(ctx)
+	return out, err
+}
+
+// UpdateStudentParams UpdateStudent 的参数
+type UpdateStudentParams struct {
+	// 必填
+	StudentId string
+}
+
+// UpdateStudent 更新学生信息
+func (c *Client) UpdateStudent(ctx context.Context, params UpdateStudentParams, body StudentInfo) error {
+	r := c.R().Method("PUT").Path("/api/v1/student/" + url.PathEscape(params.StudentId))
+	r.JSON(body)
+	_, err := r.Do(ctx)
+	return err
+}
+
+// StudentInfo 学生基本信息
+type StudentInfo struct {
+	StudentId  string    `json:"studentId" gorm:"primarykey"`
+	Name       string    `json:"name"`
+	Grade      string    `json:"grade"`
+	Gpa        float64   `json:"gpa"`
+	Enrolled   bool      `json:"enrolled"`
+	UpdateTime time.Time `json:"updatedAt" time_format:"sql_datetime" time_location:"shanghai" gorm:"index;column:updated_at"`
+}
@@ -0,0 +1,300 @@
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// schema OpenAPI 3 和 Swagger 2 共用的 Schema Object，只解析生成代码需要的部分
+type schema struct {
+	Ref         string      `json:"$ref"`
+	Type        string      `json:"type"`
+	Format      string      `json:"format"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Properties  namedSchema `json:"properties"`
+	Items       *schema     `json:"items"`
+	AllOf       []*schema   `json:"allOf"`
+	// AdditionalProperties 可能是 bool 或 schema
+	AdditionalProperties json.RawMessage `json:"additionalProperties"`
+	// PrimaryKey 扩展字段，标记模型的主键
+	PrimaryKey bool `json:"x-primary-key"`
+}
+
+// namedSchema 保持 json 中顺序的 schema 列表，生成的字段顺序与文档一致
+type namedSchema []struct {
+	Name   string
+	Schema *schema
+}
+
+func (ns *namedSchema) UnmarshalJSON(data []byte) error {
+	return decodeOrdered(data, func(key string, raw json.RawMessage) error {
+		s := new(schema)
+		if err := json.Unmarshal(raw, s); err != nil {
+			return err
+		}
+		*ns = append(*ns, struct {
+			Name   string
+			Schema *schema
+		}{key, s})
+		return nil
+	})
+}
+
+// get 按名称查找
+func (ns namedSchema) get(name string) *schema {
+	for _, item := range ns {
+		if item.Name == name {
+			return item.Schema
+		}
+	}
+	return nil
+}
+
+// decodeOrdered 按顺序遍历 json 对象的键值
+func decodeOrdered(data []byte, fn func(key string, raw json.RawMessage) error) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("expect json object, got %v", tok)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+		if err := fn(tok.(string), raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type parameter struct {
+	Ref         string  `json:"$ref"`
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description"`
+	Required    bool    `json:"required"`
+	Schema      *schema `json:"schema"`
+	// Type、Format Swagger 2 中非 body 参数直接写在参数上
+	Type   string `json:"type"`
+	Format string `json:"format"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+type response struct {
+	Ref         string               `json:"$ref"`
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content"`
+	// Schema Swagger 2
+	Schema *schema `json:"schema"`
+}
+
+type operation struct {
+	OperationId string       `json:"operationId"`
+	Summary     string       `json:"summary"`
+	Description string       `json:"description"`
+	Parameters  []*parameter `json:"parameters"`
+	RequestBody *struct {
+		Content map[string]mediaType `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]*response `json:"responses"`
+	// Paginated 扩展字段，显式标记分页接口
+	Paginated *bool `json:"x-paginated"`
+}
+
+// pathItem 路径下的各个方法，保持文档中的顺序
+type pathItem struct {
+	Parameters []*parameter
+	Operations []struct {
+		Method    string
+		Operation *operation
+	}
+}
+
+var httpMethods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true, "patch": true, "head": true, "options": true,
+}
+
+func (p *pathItem) UnmarshalJSON(data []byte) error {
+	return decodeOrdered(data, func(key string, raw json.RawMessage) error {
+		switch {
+		case key == "parameters":
+			return json.Unmarshal(raw, &p.Parameters)
+		case httpMethods[strings.ToLower(key)]:
+			op := new(operation)
+			if err := json.Unmarshal(raw, op); err != nil {
+				return err
+			}
+			p.Operations = append(p.Operations, struct {
+				Method    string
+				Operation *operation
+			}{strings.ToUpper(key), op})
+		}
+		return nil
+	})
+}
+
+type paths []struct {
+	Path string
+	Item *pathItem
+}
+
+func (ps *paths) UnmarshalJSON(data []byte) error {
+	return decodeOrdered(data, func(key string, raw json.RawMessage) error {
+		item := new(pathItem)
+		if err := json.Unmarshal(raw, item); err != nil {
+			return fmt.Errorf("parse path %s fail: %v", key, err)
+		}
+		*ps = append(*ps, struct {
+			Path string
+			Item *pathItem
+		}{key, item})
+		return nil
+	})
+}
+
+// document OpenAPI 3 或 Swagger 2 文档
+type document struct {
+	OpenAPI string `json:"openapi"`
+	Swagger string `json:"swagger"`
+	Info    struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		Version     string `json:"version"`
+	} `json:"info"`
+	BasePath   string `json:"basePath"`
+	Paths      paths  `json:"paths"`
+	Components struct {
+		Schemas    namedSchema           `json:"schemas"`
+		Parameters map[string]*parameter `json:"parameters"`
+		Responses  map[string]*response  `json:"responses"`
+	} `json:"components"`
+	Definitions namedSchema           `json:"definitions"`
+	Parameters  map[string]*parameter `json:"parameters"`
+	Responses   map[string]*response  `json:"responses"`
+}
+
+// parseDocument 解析 json 格式的文档
+func parseDocument(data []byte) (*document, error) {
+	doc := new(document)
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("parse openapi document fail: %v", err)
+	}
+	if doc.OpenAPI == "" && doc.Swagger == "" {
+		return nil, fmt.Errorf("parse openapi document fail: missing openapi or swagger version")
+	}
+	return doc, nil
+}
+
+// schemas 返回文档中定义的模型
+func (doc *document) schemas() namedSchema {
+	if doc.Swagger != "" {
+		return doc.Definitions
+	}
+	return doc.Components.Schemas
+}
+
+// refName 返回 $ref 指向的名称，例如 #/components/schemas/Organization -> Organization
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// resolve 解析 schema 的 $ref
+func (doc *document) resolve(s *schema) (*schema, error) {
+	for depth := 0; s != nil && s.Ref != ""; depth++ {
+		if depth > 32 {
+			return nil, fmt.Errorf("circular $ref: %s", s.Ref)
+		}
+		target := doc.schemas().get(refName(s.Ref))
+		if target == nil {
+			return nil, fmt.Errorf("$ref not found: %s", s.Ref)
+		}
+		s = target
+	}
+	return s, nil
+}
+
+func (doc *document) resolveParameter(p *parameter) (*parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	params := doc.Parameters
+	if doc.Swagger == "" {
+		params = doc.Components.Parameters
+	}
+	target, ok := params[refName(p.Ref)]
+	if !ok {
+		return nil, fmt.Errorf("$ref not found: %s", p.Ref)
+	}
+	return target, nil
+}
+
+func (doc *document) resolveResponse(r *response) (*response, error) {
+	if r.Ref == "" {
+		return r, nil
+	}
+	responses := doc.Responses
+	if doc.Swagger == "" {
+		responses = doc.Components.Responses
+	}
+	target, ok := responses[refName(r.Ref)]
+	if !ok {
+		return nil, fmt.Errorf("$ref not found: %s", r.Ref)
+	}
+	return target, nil
+}
+
+// jsonSchema 返回 content 中 json 格式的 schema，优先使用 application/json
+func jsonSchema(content map[string]mediaType) *schema {
+	if media, ok := content["application/json"]; ok && media.Schema != nil {
+		return media.Schema
+	}
+	types := make([]string, 0, len(content))
+	for ct := range content {
+		types = append(types, ct)
+	}
+	sort.Strings(types)
+	for _, ct := range types {
+		if strings.Contains(ct, "json") && content[ct].Schema != nil {
+			return content[ct].Schema
+		}
+	}
+	return nil
+}
+
+// successResponse 返回 2xx 响应的 schema
+func (doc *document) successResponse(op *operation) (*schema, error) {
+	for _, code := range []string{"200", "201", "2XX", "default"} {
+		r, ok := op.Responses[code]
+		if !ok {
+			continue
+		}
+		r, err := doc.resolveResponse(r)
+		if err != nil {
+			return nil, err
+		}
+		if r.Schema != nil {
+			return r.Schema, nil
+		}
+		return jsonSchema(r.Content), nil
+	}
+	return nil, nil
+}
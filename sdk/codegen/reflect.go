@@ -0,0 +1,48 @@
+package codegen
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// reflectTypes InferStruct 可能生成的字段类型
+var reflectTypes = map[string]reflect.Type{
+	"string":                 reflect.TypeOf(""),
+	"int":                    reflect.TypeOf(0),
+	"int64":                  reflect.TypeOf(int64(0)),
+	"float32":                reflect.TypeOf(float32(0)),
+	"float64":                reflect.TypeOf(float64(0)),
+	"bool":                   reflect.TypeOf(false),
+	"time.Time":              reflect.TypeOf(time.Time{}),
+	"interface{}":            reflect.TypeOf((*interface{})(nil)).Elem(),
+	"map[string]interface{}": reflect.TypeOf(map[string]interface{}{}),
+	"[]string":               reflect.TypeOf([]string{}),
+	"[]int":                  reflect.TypeOf([]int{}),
+	"[]float64":              reflect.TypeOf([]float64{}),
+	"[]bool":                 reflect.TypeOf([]bool{}),
+	"[]interface{}":          reflect.TypeOf([]interface{}{}),
+}
+
+/*
+Type 在运行时创建与生成的代码相同的结构体类型，tag 也相同，可以直接传给 SyncToDB
+
+	s, _ := codegen.InferStruct("Row", rows, []string{"id"})
+	typ, _ := s.Type()
+	model := reflect.New(reflect.SliceOf(typ)).Interface() // *[]Row
+	res, err := sdk.SyncToDB(db.Table("rows"), api, model)
+
+创建的类型没有名称，使用 gorm 时需要通过 Table 指定表名。只支持基本类型，不支持嵌套的结构体。
+*/
+func (s Struct) Type() (reflect.Type, error) {
+	names := s.fieldNames()
+	fields := make([]reflect.StructField, 0, len(s.Fields))
+	for i, f := range s.Fields {
+		typ, ok := reflectTypes[f.Type]
+		if !ok {
+			return nil, fmt.Errorf("not support field type %s of %s", f.Type, f.JSONName)
+		}
+		fields = append(fields, reflect.StructField{Name: names[i], Type: typ, Tag: reflect.StructTag(f.Tag())})
+	}
+	return reflect.StructOf(fields), nil
+}
@@ -0,0 +1,101 @@
+package sdk
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk/sdktest"
+)
+
+// 使用 go test -race 运行，检查多个 goroutine 共用一个 client 时的数据竞争
+func Test_ConcurrentRequestsWithTokenRefresh(t *testing.T) {
+	srv := newTestServer(t)
+	if _, err := srv.AddRows(testAPIPath, newTestRows(100, "2023-01-02 00:00:00")); err != nil {
+		t.Fatal(err)
+	}
+	c := GetOpenAPIClient()
+
+	const workers = 32
+	const requests = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*requests)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < requests; j++ {
+				if _, err := c.GetRows(testAPIPath, j%10+1, 10); err != nil {
+					errs <- err
+				}
+			}
+		}(i)
+	}
+
+	// 请求过程中让 token 失效几次，所有请求都应该自动重新获取 token 后成功
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 3; i++ {
+			time.Sleep(5 * time.Millisecond)
+			srv.RevokeTokens()
+		}
+	}()
+	wg.Wait()
+	<-done
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	// 每次失效最多只刷新一次 token，不会每个请求都去刷新
+	if n := srv.Requests("/oauth2/token"); n > 4 {
+		t.Errorf("token should be refreshed at most once per revocation, got %d fetches", n)
+	}
+}
+
+func Test_TokenSourceInvalidate(t *testing.T) {
+	srv := newTestServer(t)
+	ts := GetOpenAPIClient().tokens
+
+	first, err := ts.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.invalidate("stale-token")
+	if second, _ := ts.Token(); second.AccessToken != first.AccessToken {
+		t.Error("invalidating a stale token should keep the current one")
+	}
+	ts.invalidate(first.AccessToken)
+	if third, _ := ts.Token(); third.AccessToken == first.AccessToken {
+		t.Error("invalidating the current token should fetch a new one")
+	}
+	if srv.Requests("/oauth2/token") != 2 {
+		t.Errorf("token should be fetched twice, got %d", srv.Requests("/oauth2/token"))
+	}
+}
+
+func Test_RetryCount(t *testing.T) {
+	srv := newTestServer(t)
+	if _, err := srv.AddRows(testAPIPath, newTestRows(10, "2023-01-02 00:00:00")); err != nil {
+		t.Fatal(err)
+	}
+	c := GetOpenAPIClient()
+
+	// token 一直失效时重试次数累加
+	srv.InjectFault(sdktest.InvalidToken(testAPIPath, maxTokenRetry+1))
+	if _, err := c.GetRows(testAPIPath, 1, 10); err == nil {
+		t.Error("expected invalid token error")
+	}
+	if n := c.Retries(); n != maxTokenRetry || c.RetryCount != n {
+		t.Errorf("expected %d retries, got %d", maxTokenRetry, n)
+	}
+
+	// 请求成功后清零
+	if _, err := c.GetRows(testAPIPath, 1, 10); err != nil {
+		t.Fatal(err)
+	}
+	if n := c.Retries(); n != 0 || c.RetryCount != 0 {
+		t.Errorf("expected retry count reset, got %d", n)
+	}
+}
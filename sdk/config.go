@@ -0,0 +1,294 @@
+package sdk
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultEnvPrefix = "ECNU"
+
+	// secretFilePrefix 配置值以 file: 开头时，表示从文件读取，例如 docker/k8s 挂载的 secret
+	secretFilePrefix = "file:"
+	// envFileSuffix 环境变量以 _FILE 结尾时，表示其值是一个文件路径，例如 ECNU_CLIENT_SECRET_FILE
+	envFileSuffix = "_FILE"
+)
+
+// LoadConfig 从配置文件读取 OAuth2Config，支持 .json 和 .env 两种格式
+// .env 文件中的变量名与 ConfigFromEnv 一致，使用默认前缀 ECNU，例如 ECNU_CLIENT_ID
+func LoadConfig(path string) (OAuth2Config, error) {
+	var cf OAuth2Config
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return cf, fmt.Errorf("read config file fail: %v", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if err := json.Unmarshal(content, &cf); err != nil {
+			return cf, fmt.Errorf("parse config file %s fail: %v", path, err)
+		}
+		if err := cf.resolveSecrets(); err != nil {
+			return cf, err
+		}
+	case ".env":
+		vars, err := parseDotEnv(string(content))
+		if err != nil {
+			return cf, fmt.Errorf("parse config file %s fail: %v", path, err)
+		}
+		cf, err = configFromLookup(DefaultEnvPrefix, func(key string) (string, bool) {
+			v, ok := vars[key]
+			return v, ok
+		})
+		if err != nil {
+			return cf, err
+		}
+	default:
+		return cf, fmt.Errorf("not support config file: %s, only .json or .env", path)
+	}
+
+	if err := cf.Validate(); err != nil {
+		return cf, err
+	}
+	return cf, nil
+}
+
+/*
+ConfigFromEnv 从环境变量读取 OAuth2Config，prefix 为空时使用 ECNU
+
+	ECNU_CLIENT_ID       client_id
+	ECNU_CLIENT_SECRET   client_secret，也可以用 ECNU_CLIENT_SECRET_FILE 指定 secret 文件
+	ECNU_BASE_URL        默认 https://api.ecnu.edu.cn
+	ECNU_BASE_URLS       多个网关地址用逗号分隔，按顺序故障切换
+	ECNU_PROFILE         命名环境，默认 production
+	ECNU_TESTING_BASE_URL  testing 环境的网关地址，只在 ECNU_PROFILE=testing 时使用，不受 prefix 影响
+	ECNU_SCOPES          多个 scope 用逗号或空格分隔，默认 ECNU-Basic
+	ECNU_TIMEOUT         超时秒数，默认 10
+	ECNU_DEBUG           true/false
+	ECNU_REDIRECT_URL    authorization code 模式的回调地址
+	ECNU_USER_INFO_URL   authorization code 模式的用户信息地址
+	ECNU_AUTH_URL        authorization code 模式的授权地址
+	ECNU_TOKEN_URL       token 地址
+	ECNU_PROXY_URL       访问网关使用的 http 代理
+	ECNU_APP_KEY         签名认证的 AppKey，设置后不再需要 client_id 和 client_secret
+	ECNU_APP_SECRET      签名认证的 AppSecret
+
+任意变量都可以追加 _FILE 后缀，或者将值写成 file:/path/to/secret 的形式，从文件中读取。
+*/
+func ConfigFromEnv(prefix string) (OAuth2Config, error) {
+	cf, err := configFromLookup(prefix, os.LookupEnv)
+	if err != nil {
+		return cf, err
+	}
+	if err := cf.Validate(); err != nil {
+		return cf, err
+	}
+	return cf, nil
+}
+
+// Validate 校验配置，缺少必填项或者格式错误时返回错误
+func (cf OAuth2Config) Validate() error {
+	var missing []string
+	if cf.Signature.Enabled() {
+		if cf.Signature.AppSecret == "" {
+			missing = append(missing, "signature.app_secret")
+		}
+	} else {
+		if cf.ClientId == "" {
+			missing = append(missing, "client_id")
+		}
+		if cf.ClientSecret == "" {
+			missing = append(missing, "client_secret")
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("invalid config: missing %s", strings.Join(missing, ", "))
+	}
+	if cf.Timeout < 0 {
+		return fmt.Errorf("invalid config: timeout must not be negative, got %d", cf.Timeout)
+	}
+
+	urls := []struct {
+		name  string
+		value string
+	}{
+		{"base_url", cf.BaseUrl},
+		{"redirect_url", cf.RedirectURL},
+		{"user_info_url", cf.UserInfoURL},
+		{"endpoint.auth_url", cf.Endpoint.AuthURL},
+		{"endpoint.token_url", cf.Endpoint.TokenURL},
+		{"transport.proxy_url", cf.Transport.ProxyURL},
+	}
+	for _, u := range urls {
+		if err := validateURL(u.name, u.value); err != nil {
+			return err
+		}
+	}
+	for _, baseUrl := range cf.BaseUrls {
+		if err := validateURL("base_urls", baseUrl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateURL(name, value string) error {
+	if value == "" {
+		return nil
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("invalid config: %s is not a valid url: %v", name, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid config: %s must be an absolute http(s) url, got %q", name, value)
+	}
+	return nil
+}
+
+// resolveSecrets 将 file: 形式的配置值替换为文件内容
+func (cf *OAuth2Config) resolveSecrets() error {
+	fields := []*string{
+		&cf.ClientId, &cf.ClientSecret, &cf.BaseUrl, &cf.RedirectURL,
+		&cf.UserInfoURL, &cf.Endpoint.AuthURL, &cf.Endpoint.TokenURL, &cf.Transport.ProxyURL,
+		&cf.Signature.AppKey, &cf.Signature.AppSecret,
+	}
+	for _, field := range fields {
+		value, err := resolveSecret(*field)
+		if err != nil {
+			return err
+		}
+		*field = value
+	}
+	return nil
+}
+
+func resolveSecret(value string) (string, error) {
+	if !strings.HasPrefix(value, secretFilePrefix) {
+		return value, nil
+	}
+	path := strings.TrimPrefix(value, secretFilePrefix)
+	// 兼容 file:///run/secrets/xxx 的写法
+	path = strings.TrimPrefix(path, "//")
+	return readSecretFile(path)
+}
+
+func readSecretFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read secret file fail: %v", err)
+	}
+	// secret 文件通常带有结尾的换行
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+func configFromLookup(prefix string, lookup func(string) (string, bool)) (OAuth2Config, error) {
+	var cf OAuth2Config
+	if prefix == "" {
+		prefix = DefaultEnvPrefix
+	}
+	prefix = strings.TrimSuffix(prefix, "_") + "_"
+
+	get := func(name string) (string, error) {
+		key := prefix + name
+		if path, ok := lookup(key + envFileSuffix); ok && path != "" {
+			return readSecretFile(path)
+		}
+		value, _ := lookup(key)
+		return resolveSecret(value)
+	}
+
+	var err error
+	strFields := []struct {
+		name  string
+		value *string
+	}{
+		{"CLIENT_ID", &cf.ClientId},
+		{"CLIENT_SECRET", &cf.ClientSecret},
+		{"BASE_URL", &cf.BaseUrl},
+		{"PROFILE", &cf.Profile},
+		{"REDIRECT_URL", &cf.RedirectURL},
+		{"USER_INFO_URL", &cf.UserInfoURL},
+		{"AUTH_URL", &cf.Endpoint.AuthURL},
+		{"TOKEN_URL", &cf.Endpoint.TokenURL},
+		{"PROXY_URL", &cf.Transport.ProxyURL},
+		{"APP_KEY", &cf.Signature.AppKey},
+		{"APP_SECRET", &cf.Signature.AppSecret},
+	}
+	for _, f := range strFields {
+		if *f.value, err = get(f.name); err != nil {
+			return cf, err
+		}
+	}
+
+	scopes, err := get("SCOPES")
+	if err != nil {
+		return cf, err
+	}
+	cf.Scopes = strings.FieldsFunc(scopes, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+
+	baseUrls, err := get("BASE_URLS")
+	if err != nil {
+		return cf, err
+	}
+	cf.BaseUrls = strings.FieldsFunc(baseUrls, func(r rune) bool {
+		return r == ','
+	})
+
+	timeout, err := get("TIMEOUT")
+	if err != nil {
+		return cf, err
+	}
+	if timeout != "" {
+		if cf.Timeout, err = strconv.ParseInt(timeout, 10, 64); err != nil {
+			return cf, fmt.Errorf("invalid config: %sTIMEOUT must be an integer, got %q", prefix, timeout)
+		}
+	}
+
+	debug, err := get("DEBUG")
+	if err != nil {
+		return cf, err
+	}
+	if debug != "" {
+		if cf.Debug, err = strconv.ParseBool(debug); err != nil {
+			return cf, fmt.Errorf("invalid config: %sDEBUG must be a boolean, got %q", prefix, debug)
+		}
+	}
+	return cf, nil
+}
+
+// parseDotEnv 解析 .env 文件，支持 # 注释、export 前缀和引号
+func parseDotEnv(content string) (map[string]string, error) {
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("line %d: expect KEY=VALUE", lineNum)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		} else if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		vars[key] = value
+	}
+	return vars, scanner.Err()
+}
@@ -0,0 +1,105 @@
+package sdk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_LoadConfigJSON(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "secret")
+	if err := os.WriteFile(secretFile, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	configFile := filepath.Join(dir, "config.json")
+	content := `{"client_id":"id","client_secret":"file:` + secretFile + `","scopes":["ECNU-Basic","ECNU-Sync"],"timeout":30}`
+	if err := os.WriteFile(configFile, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cf, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cf.ClientId != "id" || cf.ClientSecret != "s3cret" {
+		t.Errorf("unexpected credentials: %s %s", cf.ClientId, cf.ClientSecret)
+	}
+	if len(cf.Scopes) != 2 || cf.Timeout != 30 {
+		t.Errorf("unexpected config: %+v", cf)
+	}
+}
+
+func Test_LoadConfigDotEnv(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "ecnu.env")
+	content := `
+# ECNU OpenAPI
+export ECNU_CLIENT_ID=id
+ECNU_CLIENT_SECRET="secret with space"
+ECNU_BASE_URL=https://api.example.com # 测试环境
+ECNU_SCOPES=ECNU-Basic,ECNU-Sync
+ECNU_DEBUG=true
+`
+	if err := os.WriteFile(configFile, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cf, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cf.ClientId != "id" || cf.ClientSecret != "secret with space" {
+		t.Errorf("unexpected credentials: %s %s", cf.ClientId, cf.ClientSecret)
+	}
+	if cf.BaseUrl != "https://api.example.com" || !cf.Debug {
+		t.Errorf("unexpected config: %+v", cf)
+	}
+	if len(cf.Scopes) != 2 || cf.Scopes[1] != "ECNU-Sync" {
+		t.Errorf("unexpected scopes: %v", cf.Scopes)
+	}
+}
+
+func Test_ConfigFromEnv(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_ECNU_CLIENT_ID", "id")
+	t.Setenv("TEST_ECNU_CLIENT_SECRET_FILE", secretFile)
+	t.Setenv("TEST_ECNU_SCOPES", "ECNU-Basic ECNU-Sync")
+	t.Setenv("TEST_ECNU_TIMEOUT", "5")
+
+	cf, err := ConfigFromEnv("TEST_ECNU")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cf.ClientSecret != "from-file" {
+		t.Errorf("secret should be read from file, got %q", cf.ClientSecret)
+	}
+	if len(cf.Scopes) != 2 || cf.Timeout != 5 {
+		t.Errorf("unexpected config: %+v", cf)
+	}
+
+	t.Setenv("TEST_ECNU_TIMEOUT", "five")
+	if _, err := ConfigFromEnv("TEST_ECNU"); err == nil {
+		t.Error("invalid timeout should fail")
+	}
+}
+
+func Test_ConfigValidate(t *testing.T) {
+	err := OAuth2Config{}.Validate()
+	if err == nil || !strings.Contains(err.Error(), "client_id, client_secret") {
+		t.Errorf("missing fields should be reported, got %v", err)
+	}
+
+	err = OAuth2Config{ClientId: "id", ClientSecret: "secret", BaseUrl: "api.ecnu.edu.cn"}.Validate()
+	if err == nil || !strings.Contains(err.Error(), "base_url") {
+		t.Errorf("relative base_url should be rejected, got %v", err)
+	}
+
+	if err := (OAuth2Config{ClientId: "id", ClientSecret: "secret", BaseUrl: DefaultBaseURL}).Validate(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,109 @@
+package sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+/*
+DeleteMarker 上游的删除标记
+
+增量同步使用 full=1 时，接口会同时返回已删除的数据，例如 deleted_mark 非 0 的行。
+设置后 SyncToDB 不再写入这些行，而是按主键删除表中对应的数据，使本地表只保留有效数据：
+
+  - HardDelete 为 true 时直接删除
+  - 否则模型需要有 gorm.DeletedAt 字段，使用 gorm 的软删除，恢复有效后 upsert 会清除 DeletedAt
+*/
+type DeleteMarker struct {
+	// Field 删除标记在接口数据中的字段名，例如 deleted_mark
+	Field string `json:"field"`
+	// Values 表示已删除的值，按字符串比较；为空时除 null、false、0 和空字符串以外的值都视为已删除
+	Values     []string `json:"values"`
+	HardDelete bool     `json:"hard_delete"`
+}
+
+// deleted 判断一行数据是否已被上游删除
+func (m *DeleteMarker) deleted(row interface{}) bool {
+	value, ok := rowField(row, m.Field)
+	if !ok || value == nil {
+		return false
+	}
+	s := fmt.Sprint(value)
+	if len(m.Values) == 0 {
+		return s != "" && s != "0" && s != "false"
+	}
+	for _, v := range m.Values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// split 将一页数据分为有效的行和已删除的行
+func (m *DeleteMarker) split(rows []interface{}) (active, deleted []interface{}) {
+	for _, row := range rows {
+		if m.deleted(row) {
+			deleted = append(deleted, row)
+		} else {
+			active = append(active, row)
+		}
+	}
+	return active, deleted
+}
+
+// rowField 读取一行数据中的字段，不是 map 时先转换为 json 对象
+func rowField(row interface{}, field string) (interface{}, bool) {
+	m, ok := row.(map[string]interface{})
+	if !ok {
+		b, err := json.Marshal(row)
+		if err != nil || json.Unmarshal(b, &m) != nil {
+			return nil, false
+		}
+	}
+	value, ok := m[field]
+	return value, ok
+}
+
+// markerDeleter 按主键删除带有删除标记的行
+type markerDeleter struct {
+	fields []*schema.Field
+	hard   bool
+}
+
+func newMarkerDeleter(db *gorm.DB, dataModel interface{}, m DeleteMarker) (*markerDeleter, error) {
+	if m.Field == "" {
+		return nil, errors.New("delete marker fail: field is empty")
+	}
+	s, err := parseModel(db, dataModel)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.PrimaryFields) == 0 {
+		return nil, errors.New("delete marker fail: model has no primary key")
+	}
+	if !m.HardDelete && !hasDeletedAt(s) {
+		return nil, errors.New("delete marker fail: need a gorm.DeletedAt field or HardDelete")
+	}
+	return &markerDeleter{fields: s.PrimaryFields, hard: m.HardDelete}, nil
+}
+
+// delete 删除一页中带有删除标记的行，data 为指向结构体切片的指针，返回实际删除的行数
+func (d *markerDeleter) delete(db *gorm.DB, data interface{}) (int64, error) {
+	keys := primaryKeys(d.fields, data)
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	elemType := reflect.Indirect(reflect.ValueOf(data)).Type().Elem()
+	// db 可能通过 Table 指定了数据表，使用可以复用的会话，避免条件互相影响
+	count, err := deleteByKeys(db.Session(&gorm.Session{}), elemType, columnNames(d.fields), keys, d.hard)
+	if err != nil {
+		return count, fmt.Errorf("delete marked rows fail: %v", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,94 @@
+package sdk
+
+import (
+	"testing"
+)
+
+// newMarkedClient 返回上游有 n 行数据的 MemoryClient，marked 中的行带有删除标记
+func newMarkedClient(n int, marked ...int) *MemoryClient {
+	rows := newTestRows(n, "2023-01-02 00:00:00")
+	for _, id := range marked {
+		rows[id-1]["deleted_mark"] = 1
+	}
+	return newTestClient(rows)
+}
+
+func Test_DeleteMarkerHardDelete(t *testing.T) {
+	db := newTestDB(t)
+	api := APIConfig{APIPath: testAPIPath, PageSize: 2, DeleteMarker: &DeleteMarker{Field: "deleted_mark", HardDelete: true}}
+	m := NewExpvarMetrics("")
+	api.Metrics = m
+
+	// 首次同步时带有删除标记的行不会写入
+	res, err := newMarkedClient(5, 2).SyncToDB(db, api, &[]testFakeRow{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total int64
+	db.Model(&testFakeRow{}).Count(&total)
+	if res.Rows != 5 || total != 4 {
+		t.Errorf("expected 4 of 5 rows, got %d %d", total, res.Rows)
+	}
+
+	// 之后被标记删除的行从表中删除
+	if _, err := newMarkedClient(5, 2, 3, 4).SyncToDB(db, api, &[]testFakeRow{}); err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	db.Model(&testFakeRow{}).Order("id").Pluck("id", &ids)
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 5 {
+		t.Errorf("unexpected rows: %v", ids)
+	}
+	if got := m.Counter("ecnu_sync_deleted_total", "api", testAPIPath, "target", "db"); got != 2 {
+		t.Errorf("expected 2 deleted rows, got %v", got)
+	}
+}
+
+func Test_DeleteMarkerSoftDelete(t *testing.T) {
+	db := newTestDB(t)
+	api := APIConfig{APIPath: testAPIPath, DeleteMarker: &DeleteMarker{Field: "deleted_mark", Values: []string{"1"}}}
+	countRows := func() (active, all int64) {
+		db.Model(&testSoftRow{}).Count(&active)
+		db.Unscoped().Model(&testSoftRow{}).Count(&all)
+		return active, all
+	}
+
+	if _, err := newMarkedClient(3).SyncToDB(db, api, &[]testSoftRow{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newMarkedClient(3, 1).SyncToDB(db, api, &[]testSoftRow{}); err != nil {
+		t.Fatal(err)
+	}
+	if active, all := countRows(); active != 2 || all != 3 {
+		t.Errorf("expected 2 of 3 rows, got %d %d", active, all)
+	}
+
+	// 删除标记取消后恢复
+	if _, err := newMarkedClient(3).SyncToDB(db, api, &[]testSoftRow{}); err != nil {
+		t.Fatal(err)
+	}
+	if active, _ := countRows(); active != 3 {
+		t.Errorf("expected 3 rows, got %d", active)
+	}
+
+	// 没有 DeletedAt 字段时需要 HardDelete
+	if _, err := newMarkedClient(3).SyncToDB(db, api, &[]testFakeRow{}); err == nil {
+		t.Error("expected delete marker error")
+	}
+}
+
+func Test_DeleteMarkerValues(t *testing.T) {
+	m := DeleteMarker{Field: "status"}
+	for value, want := range map[interface{}]bool{nil: false, 0: false, "": false, false: false, "0": false, 1: true, 2.0: true, "D": true, true: true} {
+		if got := m.deleted(map[string]interface{}{"status": value}); got != want {
+			t.Errorf("%v: expected %v, got %v", value, want, got)
+		}
+	}
+	m.Values = []string{"D", "X"}
+	if !m.deleted(map[string]interface{}{"status": "X"}) || m.deleted(map[string]interface{}{"status": 1}) {
+		t.Error("unexpected deleted values")
+	}
+	if m.deleted(map[string]interface{}{"id": 1}) {
+		t.Error("row without marker should not be deleted")
+	}
+}
@@ -0,0 +1,399 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultDownloadResumes 下载中断后默认的续传次数
+const DefaultDownloadResumes = 3
+
+// DownloadOptions 下载选项
+type DownloadOptions struct {
+	// ContentTypes 允许的 Content-Type，可以只写前缀，例如 image/、application/pdf，为空时不校验
+	// 无论是否配置，返回 APIResult 格式 json 且 errCode 非 0 的响应都会作为错误返回
+	ContentTypes []string
+	// Checksum 期望的校验值，格式为 算法:十六进制，支持 md5、sha1、sha256，例如 sha256:9f86d0...
+	// 响应带有 Content-MD5 且为完整内容时，也会自动校验
+	Checksum string
+	// Offset 从指定的字节开始下载，用于续传已经写入了一部分的文件
+	Offset int64
+	// MaxResumes 连接中断后使用 Range 请求续传的次数，默认 3，小于 0 时不续传
+	MaxResumes int
+	// Resume DownloadFile 时从已存在文件的末尾续传，默认清空已存在的文件后重新下载
+	Resume bool
+	// Timeout 整个下载（包括续传）的超时时间，默认不限制，只受 ctx 控制
+	// 下载不使用 OAuth2Config.Timeout，大文件不会因为单个请求的超时而中断
+	Timeout time.Duration
+}
+
+// noTimeoutKey context 中带有该 key 的请求不使用 client 的 Timeout
+type noTimeoutKey struct{}
+
+func (opts *DownloadOptions) setDefault() {
+	if opts.MaxResumes == 0 {
+		opts.MaxResumes = DefaultDownloadResumes
+	}
+	if opts.MaxResumes < 0 {
+		opts.MaxResumes = 0
+	}
+}
+
+// DownloadTo 下载二进制内容（照片、pdf、导出的表格等）写入 w，返回写入的字节数
+// path 可以是相对于 BaseUrl 的路径，也可以是完整的地址
+func (c *OAuth2Client) DownloadTo(ctx context.Context, path string, w io.Writer) (int64, error) {
+	return c.Download(ctx, path, w, DownloadOptions{})
+}
+
+/*
+Download 按 opts 下载二进制内容写入 w，返回本次写入的字节数
+
+连接中断时会使用 Range 请求从已写入的位置继续下载，服务端不支持 Range 时会跳过已经写入的部分。
+如果首个响应带有 ETag，续传时会通过 If-Range 确认文件没有变化。
+Offset 大于 0 时 w 中没有完整的内容，无法计算 Checksum，需要校验时请使用 DownloadFile。
+*/
+func (c *OAuth2Client) Download(ctx context.Context, path string, w io.Writer, opts DownloadOptions) (int64, error) {
+	sum, err := newChecksum(opts.Checksum)
+	if err != nil {
+		return 0, err
+	}
+	if sum != nil && opts.Offset > 0 {
+		return 0, errors.New("checksum can not be verified when offset > 0, use DownloadFile instead")
+	}
+	return c.download(ctx, path, w, opts, sum)
+}
+
+/*
+DownloadFile 下载到本地文件，Offset 会被忽略
+
+默认清空已存在的文件后重新下载；opts.Resume 为 true 时从文件末尾续传，
+文件已经完整时服务端返回 416，此时不再下载，只校验已有的内容。
+*/
+func (c *OAuth2Client) DownloadFile(ctx context.Context, path, fileName string, opts DownloadOptions) (int64, error) {
+	sum, err := newChecksum(opts.Checksum)
+	if err != nil {
+		return 0, err
+	}
+	flag := os.O_RDWR | os.O_CREATE
+	if !opts.Resume {
+		flag |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(fileName, flag, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("open download file fail: %v", err)
+	}
+	defer f.Close()
+
+	// 续传时先对已有的内容计算校验值，读完后文件指针位于末尾
+	var existing io.Writer = io.Discard
+	if sum != nil {
+		existing = sum.hash
+	}
+	if opts.Offset, err = io.Copy(existing, f); err != nil {
+		return 0, fmt.Errorf("read download file fail: %v", err)
+	}
+	return c.download(ctx, path, f, opts, sum)
+}
+
+func (c *OAuth2Client) download(ctx context.Context, path string, w io.Writer, opts DownloadOptions, sum *checksum) (int64, error) {
+	opts.setDefault()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	// 二进制内容不经过响应缓存，也不使用 client 的 Timeout
+	ctx = context.WithValue(withoutCache(ctx), noTimeoutKey{}, true)
+	u, err := c.R().Path(path).URL()
+	if err != nil {
+		return 0, err
+	}
+
+	var written int64
+	var etag string
+	// 完整内容的 Content-MD5，只有从头下载时才能校验
+	var contentMD5 *checksum
+	for resume := 0; ; resume++ {
+		header := map[string]string{}
+		offset := opts.Offset + written
+		if offset > 0 {
+			header["Range"] = fmt.Sprintf("bytes=%d-", offset)
+			if etag != "" {
+				header["If-Range"] = etag
+			}
+		}
+		result, err := c.send(ctx, u, http.MethodGet, header, nil)
+		if err != nil {
+			return written, err
+		}
+		if result.StatusCode == http.StatusRequestedRangeNotSatisfiable && resume == 0 && offset > 0 {
+			// 已有的内容就是完整的文件
+			result.Body.Close()
+			if size, ok := contentRangeSize(result.Header.Get("Content-Range")); !ok || size != offset {
+				return written, fmt.Errorf("download %s fail: offset %d is beyond content range %q", path, offset, result.Header.Get("Content-Range"))
+			}
+			break
+		}
+		contentTypes := opts.ContentTypes
+		if resume > 0 {
+			contentTypes = nil
+		}
+		if err := checkDownloadResponse(result, contentTypes); err != nil {
+			return written, err
+		}
+		if resume == 0 {
+			etag = result.Header.Get("ETag")
+			if value := result.Header.Get("Content-MD5"); value != "" && result.StatusCode == http.StatusOK && opts.Offset == 0 {
+				if expected, err := base64.StdEncoding.DecodeString(value); err == nil {
+					contentMD5 = &checksum{algorithm: "Content-MD5", expected: hex.EncodeToString(expected), hash: md5.New()}
+				}
+			}
+		}
+
+		switch result.StatusCode {
+		case http.StatusPartialContent:
+			if start, ok := contentRangeStart(result.Header.Get("Content-Range")); !ok || start != offset {
+				result.Body.Close()
+				return written, fmt.Errorf("download %s fail: unexpected Content-Range %q", path, result.Header.Get("Content-Range"))
+			}
+		case http.StatusOK:
+			if offset > 0 {
+				if resume > 0 && etag != "" && result.Header.Get("ETag") != etag {
+					result.Body.Close()
+					return written, fmt.Errorf("download %s fail: content changed while resuming", path)
+				}
+				// 服务端不支持 Range，跳过已经写入的部分
+				if _, err := io.CopyN(io.Discard, result.Body, offset); err != nil {
+					result.Body.Close()
+					return written, fmt.Errorf("download %s fail: %v", path, err)
+				}
+			}
+		}
+
+		n, readErr, writeErr := copyBody(w, result.Body, sum, contentMD5)
+		result.Body.Close()
+		written += n
+		if writeErr != nil {
+			return written, fmt.Errorf("write download content fail: %v", writeErr)
+		}
+		if readErr == nil {
+			break
+		}
+		if ctx.Err() != nil || resume >= opts.MaxResumes {
+			return written, fmt.Errorf("download %s fail: %v", path, readErr)
+		}
+	}
+
+	for _, cs := range []*checksum{sum, contentMD5} {
+		if err := cs.verify(); err != nil {
+			return written, fmt.Errorf("download %s fail: %v", path, err)
+		}
+	}
+	return written, nil
+}
+
+// checkDownloadResponse 校验状态码和 Content-Type，失败时关闭响应体
+func checkDownloadResponse(result *http.Response, contentTypes []string) error {
+	if result.StatusCode != http.StatusOK && result.StatusCode != http.StatusPartialContent {
+		_, err := parseApiResult(result, false)
+		result.Body.Close()
+		if err == nil {
+			err = fmt.Errorf("download fail, status: %d", result.StatusCode)
+		}
+		return err
+	}
+	mediaType, _, _ := mime.ParseMediaType(result.Header.Get("Content-Type"))
+	// 网关以 json 返回的业务错误
+	if mediaType == "application/json" && !matchContentType(mediaType, contentTypes) {
+		apiResult, err := parseApiResult(result, false)
+		if err != nil {
+			return err
+		}
+		if apiResult.ErrCode != 0 {
+			return &APIError{ErrCode: apiResult.ErrCode, ErrMsg: apiResult.ErrMsg, RequestId: apiResult.RequestId}
+		}
+		return fmt.Errorf("download fail: unexpected content type %s", mediaType)
+	}
+	if len(contentTypes) > 0 && !matchContentType(mediaType, contentTypes) {
+		result.Body.Close()
+		return fmt.Errorf("download fail: unexpected content type %s", mediaType)
+	}
+	return nil
+}
+
+func matchContentType(mediaType string, contentTypes []string) bool {
+	for _, ct := range contentTypes {
+		if mediaType == ct || (strings.HasSuffix(ct, "/") && strings.HasPrefix(mediaType, ct)) {
+			return true
+		}
+	}
+	return false
+}
+
+// contentRangeStart 解析 Content-Range: bytes 100-199/200 中的起始位置
+func contentRangeStart(value string) (int64, bool) {
+	value = strings.TrimPrefix(value, "bytes ")
+	start, _, found := strings.Cut(value, "-")
+	if !found {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(start, 10, 64)
+	return n, err == nil
+}
+
+// contentRangeSize 解析 416 响应中 Content-Range: bytes */200 的文件大小
+func contentRangeSize(value string) (int64, bool) {
+	_, size, found := strings.Cut(value, "/")
+	if !found {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(size, 10, 64)
+	return n, err == nil
+}
+
+type checksum struct {
+	algorithm string
+	expected  string
+	hash      hash.Hash
+}
+
+func newChecksum(value string) (*checksum, error) {
+	if value == "" {
+		return nil, nil
+	}
+	algorithm, expected, found := strings.Cut(value, ":")
+	if !found {
+		return nil, fmt.Errorf("invalid checksum %q, expect algorithm:hex", value)
+	}
+	c := &checksum{algorithm: strings.ToLower(algorithm), expected: strings.ToLower(expected)}
+	switch c.algorithm {
+	case "md5":
+		c.hash = md5.New()
+	case "sha1":
+		c.hash = sha1.New()
+	case "sha256":
+		c.hash = sha256.New()
+	default:
+		return nil, fmt.Errorf("not support checksum algorithm: %s", algorithm)
+	}
+	return c, nil
+}
+
+// verify 校验内容，c 为 nil 时不校验
+func (c *checksum) verify() error {
+	if c == nil {
+		return nil
+	}
+	if sum := hex.EncodeToString(c.hash.Sum(nil)); sum != c.expected {
+		return fmt.Errorf("%s checksum mismatch, expect %s, got %s", c.algorithm, c.expected, sum)
+	}
+	return nil
+}
+
+// copyBody 复制响应体并计算校验值，分别返回读和写的错误，读错误时可以续传
+func copyBody(w io.Writer, body io.Reader, sums ...*checksum) (int64, error, error) {
+	buf := make([]byte, 32*1024)
+	var n int64
+	for {
+		nr, readErr := body.Read(buf)
+		if nr > 0 {
+			nw, err := w.Write(buf[:nr])
+			n += int64(nw)
+			if err == nil && nw != nr {
+				err = io.ErrShortWrite
+			}
+			if err != nil {
+				return n, nil, err
+			}
+			for _, sum := range sums {
+				if sum != nil {
+					sum.hash.Write(buf[:nr])
+				}
+			}
+		}
+		if readErr == io.EOF {
+			return n, nil, nil
+		}
+		if readErr != nil {
+			return n, readErr, nil
+		}
+	}
+}
+
+// UploadFile multipart/form-data 请求中的一个文件
+type UploadFile struct {
+	FieldName string
+	FileName  string
+	// ContentType 默认 application/octet-stream
+	ContentType string
+	Reader      io.Reader
+}
+
+// Multipart 设置 multipart/form-data 请求体
+// 为了在 token 失效时重新发送，文件内容会被完整读入内存，不适合上传过大的文件
+func (r *Request) Multipart(fields map[string]string, files ...UploadFile) *Request {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for key, value := range fields {
+		if err := mw.WriteField(key, value); err != nil {
+			r.err = fmt.Errorf("create multipart body fail: %v", err)
+			return r
+		}
+	}
+	for _, file := range files {
+		contentType := file.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			escapeQuotes(file.FieldName), escapeQuotes(file.FileName)))
+		h.Set("Content-Type", contentType)
+		part, err := mw.CreatePart(h)
+		if err == nil {
+			_, err = io.Copy(part, file.Reader)
+		}
+		if err != nil {
+			r.err = fmt.Errorf("create multipart body fail: %v", err)
+			return r
+		}
+	}
+	if err := mw.Close(); err != nil {
+		r.err = fmt.Errorf("create multipart body fail: %v", err)
+		return r
+	}
+	r.body = buf.Bytes()
+	r.header["Content-Type"] = mw.FormDataContentType()
+	return r
+}
+
+// Upload 以 multipart/form-data 上传文件，返回值与 Request.Do 相同
+func (c *OAuth2Client) Upload(ctx context.Context, path string, fields map[string]string, files ...UploadFile) (*Response, error) {
+	return c.R().Path(path).Multipart(fields, files...).Do(ctx)
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
@@ -0,0 +1,235 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk/sdktest"
+)
+
+const testFilePath = "/api/v1/photo/download"
+
+func newTestContent(n int) []byte {
+	content := make([]byte, n)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+	return content
+}
+
+func sha256Checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func Test_DownloadTo(t *testing.T) {
+	srv := newTestServer(t)
+	content := newTestContent(5000)
+	srv.AddFile(testFilePath, content, "image/jpeg").ContentMD5 = true
+	srv.InjectFault(sdktest.InvalidToken(testFilePath, 1))
+	c := GetOpenAPIClient()
+
+	var buf bytes.Buffer
+	n, err := c.DownloadTo(context.Background(), testFilePath, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(content)) || !bytes.Equal(buf.Bytes(), content) {
+		t.Errorf("unexpected content, got %d bytes", n)
+	}
+	if srv.Requests("/oauth2/token") != 2 {
+		t.Errorf("token should be refreshed after A401OT, got %d fetches", srv.Requests("/oauth2/token"))
+	}
+}
+
+func Test_DownloadResume(t *testing.T) {
+	for _, disableRange := range []bool{false, true} {
+		srv := newTestServer(t)
+		content := newTestContent(5000)
+		srv.AddFile(testFilePath, content, "application/pdf").DisableRange = disableRange
+		srv.InjectFault(sdktest.TruncatedDownload(testFilePath, 2, 1000))
+
+		var buf bytes.Buffer
+		n, err := GetOpenAPIClient().Download(context.Background(), testFilePath, &buf, DownloadOptions{
+			ContentTypes: []string{"application/pdf"},
+			Checksum:     sha256Checksum(content),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != int64(len(content)) || !bytes.Equal(buf.Bytes(), content) {
+			t.Errorf("unexpected content, got %d bytes", n)
+		}
+		if srv.Requests(testFilePath) != 3 {
+			t.Errorf("download should be resumed twice, got %d requests", srv.Requests(testFilePath))
+		}
+		// 不支持 Range 时每次都从头返回，第二次中断时只跳过了已写入的部分
+		wantRange := "bytes=2000-"
+		if disableRange {
+			wantRange = "bytes=1000-"
+		}
+		header := srv.LastHeader(testFilePath)
+		if header.Get("Range") != wantRange || header.Get("If-Range") == "" {
+			t.Errorf("resume should send Range and If-Range, got %q %q", header.Get("Range"), header.Get("If-Range"))
+		}
+	}
+
+	// 超过续传次数
+	srv := newTestServer(t)
+	srv.AddFile(testFilePath, newTestContent(5000), "application/pdf")
+	srv.InjectFault(sdktest.TruncatedDownload(testFilePath, 0, 1000))
+	_, err := GetOpenAPIClient().Download(context.Background(), testFilePath, &bytes.Buffer{}, DownloadOptions{MaxResumes: -1})
+	if err == nil {
+		t.Error("truncated download without resume should fail")
+	}
+}
+
+func Test_DownloadErrors(t *testing.T) {
+	srv := newTestServer(t)
+	content := newTestContent(100)
+	srv.AddFile(testFilePath, content, "application/pdf")
+	srv.AddData(testOrgPath, []string{"0445"})
+	c := GetOpenAPIClient()
+	ctx := context.Background()
+
+	cases := []struct {
+		name string
+		path string
+		opts DownloadOptions
+		want string
+	}{
+		{"content type", testFilePath, DownloadOptions{ContentTypes: []string{"image/"}}, "unexpected content type application/pdf"},
+		{"checksum", testFilePath, DownloadOptions{Checksum: "md5:00000000000000000000000000000000"}, "md5 checksum mismatch"},
+		{"bad checksum", testFilePath, DownloadOptions{Checksum: "crc32:00"}, "not support checksum algorithm"},
+		{"json response", testOrgPath, DownloadOptions{}, "unexpected content type application/json"},
+		{"gateway error", "/api/v1/not-found", DownloadOptions{}, "A404NF"},
+	}
+	for _, cs := range cases {
+		_, err := c.Download(ctx, cs.path, &bytes.Buffer{}, cs.opts)
+		if err == nil || !strings.Contains(err.Error(), cs.want) {
+			t.Errorf("%s: expected error %q, got %v", cs.name, cs.want, err)
+		}
+	}
+}
+
+func Test_DownloadFile(t *testing.T) {
+	srv := newTestServer(t)
+	content := newTestContent(5000)
+	srv.AddFile(testFilePath, content, "application/pdf")
+
+	fileName := filepath.Join(t.TempDir(), "photo.pdf")
+	if err := os.WriteFile(fileName, content[:1000], 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := DownloadOptions{Checksum: sha256Checksum(content), Resume: true}
+	n, err := GetOpenAPIClient().DownloadFile(context.Background(), testFilePath, fileName, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(fileName)
+	if n != 4000 || !bytes.Equal(got, content) {
+		t.Errorf("unexpected file content, downloaded %d bytes", n)
+	}
+	header := srv.LastHeader(testFilePath)
+	if r := header.Get("Range"); r != "bytes=1000-" {
+		t.Errorf("existing file should be resumed, got Range %q", r)
+	}
+	if cc := header.Get("Cache-Control"); cc != "" {
+		t.Errorf("download should not send Cache-Control, got %q", cc)
+	}
+
+	// 文件已经完整时服务端返回 416
+	if n, err = GetOpenAPIClient().DownloadFile(context.Background(), testFilePath, fileName, opts); err != nil || n != 0 {
+		t.Errorf("complete file should not be downloaded again, got %d %v", n, err)
+	}
+
+	// 默认清空已存在的文件重新下载
+	if err := os.WriteFile(fileName, []byte("stale content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if n, err = GetOpenAPIClient().DownloadFile(context.Background(), testFilePath, fileName, DownloadOptions{}); err != nil || n != 5000 {
+		t.Errorf("expected fresh download, got %d %v", n, err)
+	}
+	if got, _ := os.ReadFile(fileName); !bytes.Equal(got, content) {
+		t.Error("stale file should be replaced")
+	}
+}
+
+func Test_DownloadTimeout(t *testing.T) {
+	srv := sdktest.NewServer()
+	t.Cleanup(srv.Close)
+	err := SetupOAuth2ClientCredentials(OAuth2Config{
+		ClientId:     srv.ClientId,
+		ClientSecret: srv.ClientSecret,
+		BaseUrl:      srv.URL,
+		Transport:    TransportConfig{RequestTimeout: 50 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.AddFile(testFilePath, newTestContent(100), "image/jpeg")
+	c := GetOpenAPIClient()
+
+	// 下载不受 client 的超时影响
+	srv.InjectFault(sdktest.Fault{Path: testFilePath, Times: 1, Delay: 150 * time.Millisecond})
+	if _, err := c.DownloadTo(context.Background(), testFilePath, io.Discard); err != nil {
+		t.Errorf("download should not use the client timeout, got %v", err)
+	}
+
+	srv.InjectFault(sdktest.Fault{Path: testFilePath, Times: 1, Delay: 150 * time.Millisecond})
+	_, err = c.Download(context.Background(), testFilePath, io.Discard, DownloadOptions{Timeout: 50 * time.Millisecond})
+	if err == nil || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("download should fail after its own timeout, got %v", err)
+	}
+}
+
+func Test_Upload(t *testing.T) {
+	const uploadPath = "/api/v1/photo/upload"
+	srv := newTestServer(t)
+	srv.AddUpload(uploadPath)
+	srv.InjectFault(sdktest.InvalidToken(uploadPath, 1))
+
+	var count int
+	resp, err := GetOpenAPIClient().R().
+		Path(uploadPath).
+		Multipart(map[string]string{"userId": "10000000000"},
+			UploadFile{FieldName: "photo", FileName: "a.jpg", ContentType: "image/jpeg", Reader: bytes.NewReader([]byte("jpeg"))},
+			UploadFile{FieldName: "attachment", FileName: `说明 "1".txt`, Reader: strings.NewReader("text")},
+		).
+		Into(&count).
+		Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 || resp.RequestId == "" {
+		t.Errorf("unexpected response: %d %+v", count, resp)
+	}
+
+	// 第一次请求返回 A401OT，重新获取 token 后再次发送完整的请求体
+	uploads := srv.Uploads(uploadPath)
+	if len(uploads) != 1 {
+		t.Fatalf("expected 1 upload, got %d", len(uploads))
+	}
+	files := make(map[string]sdktest.UploadedFile)
+	for _, f := range uploads[0].Files {
+		files[f.FieldName] = f
+	}
+	if uploads[0].Fields["userId"] != "10000000000" {
+		t.Errorf("unexpected fields: %v", uploads[0].Fields)
+	}
+	if f := files["photo"]; f.FileName != "a.jpg" || f.ContentType != "image/jpeg" || string(f.Content) != "jpeg" {
+		t.Errorf("unexpected photo: %+v", f)
+	}
+	if f := files["attachment"]; f.FileName != `说明 "1".txt` || f.ContentType != "application/octet-stream" || string(f.Content) != "text" {
+		t.Errorf("unexpected attachment: %+v", f)
+	}
+}
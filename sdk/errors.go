@@ -0,0 +1,63 @@
+package sdk
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+GatewayError 网关返回的非 200 响应
+
+Code 为 X-Ca-Error-Code，例如 A401OT（access_token 错误）、A403PR（没有权限）。
+调用方可以通过 errors.As 判断错误类型：
+
+	var gwErr *sdk.GatewayError
+	if errors.As(err, &gwErr) && gwErr.Auth() {
+		// 重新授权
+	}
+*/
+type GatewayError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	RequestId  string
+}
+
+func (e *GatewayError) Error() string {
+	return fmt.Sprintf("invoke api get fail, X-Ca-Error-Code: %s, X-Ca-Error-Message: %s,X-Ca-Request-Id: %s",
+		e.Code, e.Message, e.RequestId)
+}
+
+// Auth 是否为认证或授权失败，即 A401、A403 开头的错误码
+func (e *GatewayError) Auth() bool {
+	return strings.HasPrefix(e.Code, "A401") || strings.HasPrefix(e.Code, "A403")
+}
+
+// APIError 接口返回的 errCode 不为 0，Error 只返回 errMsg
+type APIError struct {
+	ErrCode   int64
+	ErrMsg    string
+	RequestId string
+}
+
+func (e *APIError) Error() string {
+	return e.ErrMsg
+}
+
+/*
+RequestError 请求没有得到响应，例如网络不通、超时，或者获取 token 失败
+
+获取 token 时网关拒绝了请求，Err 中包含 *oauth2.RetrieveError。
+*/
+type RequestError struct {
+	Method string
+	Err    error
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("invoke api %s fail: %v", strings.ToLower(e.Method), e.Err)
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,142 @@
+package sdk
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultFailoverCooldown 节点失败后，在这段时间内优先使用其他节点
+	DefaultFailoverCooldown = 30 * time.Second
+)
+
+/*
+failoverTransport 按顺序在多个网关节点间切换
+
+发往主地址的请求，会按 baseUrls 的顺序依次尝试，跳过冷却期内的故障节点。
+网络错误或者 502/503/504 视为节点故障，节点会进入冷却期，请求成功后恢复健康。
+如果所有节点都在冷却期内，仍然会按顺序全部尝试一遍。
+*/
+type failoverTransport struct {
+	next     http.RoundTripper
+	bases    []*url.URL
+	cooldown time.Duration
+
+	mu        sync.Mutex
+	downUntil []time.Time
+}
+
+func newFailoverTransport(baseUrls []string, cooldown time.Duration, next http.RoundTripper) (*failoverTransport, error) {
+	if cooldown <= 0 {
+		cooldown = DefaultFailoverCooldown
+	}
+	t := &failoverTransport{next: next, cooldown: cooldown}
+	for _, baseUrl := range baseUrls {
+		u, err := url.Parse(baseUrl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base url %s: %v", baseUrl, err)
+		}
+		t.bases = append(t.bases, u)
+	}
+	t.downUntil = make([]time.Time, len(t.bases))
+	return t, nil
+}
+
+func (t *failoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	primary := t.bases[0]
+	if req.URL.Host != primary.Host || !strings.HasPrefix(req.URL.Path, primary.Path) {
+		return next.RoundTrip(req)
+	}
+
+	var resp *http.Response
+	var err error
+	for i, idx := range t.order() {
+		if i > 0 {
+			// 请求体无法重放时，不能切换到下一个节点
+			if req.Body != nil && req.GetBody == nil {
+				break
+			}
+			// 上一个节点的响应不再使用，需要关闭
+			if resp != nil {
+				resp.Body.Close()
+			}
+		}
+		r, rerr := t.rewrite(req, idx)
+		if rerr != nil {
+			return nil, rerr
+		}
+		resp, err = next.RoundTrip(r)
+		if err == nil && !isNodeFailure(resp.StatusCode) {
+			t.markUp(idx)
+			return resp, nil
+		}
+		t.markDown(idx)
+		if req.Context().Err() != nil {
+			break
+		}
+	}
+	return resp, err
+}
+
+// order 返回本次请求尝试节点的顺序，健康节点在前
+func (t *failoverTransport) order() []int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	var healthy, down []int
+	for i := range t.bases {
+		if now.Before(t.downUntil[i]) {
+			down = append(down, i)
+		} else {
+			healthy = append(healthy, i)
+		}
+	}
+	return append(healthy, down...)
+}
+
+func (t *failoverTransport) markDown(idx int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.downUntil[idx] = time.Now().Add(t.cooldown)
+}
+
+func (t *failoverTransport) markUp(idx int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.downUntil[idx] = time.Time{}
+}
+
+func (t *failoverTransport) rewrite(req *http.Request, idx int) (*http.Request, error) {
+	if idx == 0 && req.Body == nil {
+		return req, nil
+	}
+	r := req.Clone(req.Context())
+	if req.Body != nil && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	base := t.bases[idx]
+	r.URL.Scheme = base.Scheme
+	r.URL.Host = base.Host
+	r.URL.Path = base.Path + strings.TrimPrefix(req.URL.Path, t.bases[0].Path)
+	r.URL.RawPath = ""
+	r.Host = ""
+	return r, nil
+}
+
+func isNodeFailure(statusCode int) bool {
+	return statusCode == http.StatusBadGateway ||
+		statusCode == http.StatusServiceUnavailable ||
+		statusCode == http.StatusGatewayTimeout
+}
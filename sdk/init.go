@@ -2,10 +2,13 @@ package sdk
 
 import (
 	"context"
+	"log"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"golang.org/x/oauth2"
 	cc "golang.org/x/oauth2/clientcredentials"
 )
 
@@ -27,11 +30,36 @@ var (
 )
 
 type OAuth2Client struct {
-	conf       *cc.Config
-	Client     *http.Client
-	BaseUrl    string
+	tokens  *tokenSource
+	metrics Metrics
+	tracer  Tracer
+	retries atomic.Int64
+	Client  *http.Client
+	BaseUrl string
+	// Deprecated: RetryCount 与 Retries 的值相同，只为兼容保留，并发请求时直接读取字段存在数据竞争，请使用 Retries
 	RetryCount int
 	Debug      bool
+	retryLock  sync.Mutex
+}
+
+// Retries 最近因为 access_token 失效（A401OT）而连续重试的次数，请求成功后清零
+func (c *OAuth2Client) Retries() int {
+	return int(c.retries.Load())
+}
+
+// retryAdd 重试次数加一，同时写入兼容的 RetryCount 字段
+func (c *OAuth2Client) retryAdd() {
+	c.retryLock.Lock()
+	defer c.retryLock.Unlock()
+	c.RetryCount = int(c.retries.Add(1))
+}
+
+// retryReset 重试次数清零
+func (c *OAuth2Client) retryReset() {
+	c.retryLock.Lock()
+	defer c.retryLock.Unlock()
+	c.retries.Store(0)
+	c.RetryCount = 0
 }
 
 type EndpointConf struct {
@@ -52,38 +80,117 @@ type OAuth2Config struct {
 	BaseUrl string `json:"base_url"`
 	Timeout int64  `json:"timeout"`
 
+	// Profile 命名环境：production、custom 或通过 RegisterProfile 注册的环境，默认 production
+	Profile string `json:"profile"`
+	// BaseUrls 按顺序排列的网关地址，第一个为主地址，其余在主地址故障时依次切换
+	BaseUrls []string `json:"base_urls"`
+	// FailoverCooldown 网关节点故障后的冷却时间，默认 30 秒
+	FailoverCooldown time.Duration `json:"failover_cooldown"`
+
 	RedirectURL string       `json:"redirect_url"`
 	UserInfoURL string       `json:"user_info_url"`
 	Endpoint    EndpointConf `json:"endpoint"`
 
 	Cache CacheConfig `json:"cache"`
+
+	// Signature 配置了 AppKey 时使用网关签名认证，不再获取 OAuth2 token
+	Signature SignatureConfig `json:"signature"`
+
+	// Cassette 录制/回放接口请求，用于离线测试
+	Cassette CassetteConfig `json:"cassette"`
+	// ResponseCache GET 请求的响应缓存，默认关闭
+	ResponseCache ResponseCacheConfig `json:"response_cache"`
+	// Transport 代理、证书、连接池和超时等传输配置，同时作用于获取 token 和调用接口
+	Transport TransportConfig `json:"transport"`
+	// Metrics 接口调用、token 获取和数据同步的指标，默认不记录
+	Metrics Metrics `json:"-"`
+	// Tracer 接口调用、token 获取和数据同步的链路追踪，默认不记录
+	Tracer Tracer `json:"-"`
 }
 
-// Init 初始化 OAuth2 应用
+// Init 初始化 OAuth2 应用，配置了 Signature.AppKey 时使用签名认证
+// 配置有误时通过 log 输出错误并保留之前的 client，需要处理错误时使用 SetupOAuth2ClientCredentials
 func InitOAuth2ClientCredentials(cf OAuth2Config) {
-	baseUrl := DefaultBaseURL
-	scopes := []string{DefaultScope}
-	var timeout int64 = DefaultTimeout
+	if err := SetupOAuth2ClientCredentials(cf); err != nil {
+		log.Printf("初始化失败: %v", err)
+	}
+}
 
-	if cf.BaseUrl != "" {
-		baseUrl = cf.BaseUrl
+// SetupOAuth2ClientCredentials 与 InitOAuth2ClientCredentials 相同，配置有误时返回错误
+func SetupOAuth2ClientCredentials(cf OAuth2Config) error {
+	profile, baseUrls, err := cf.resolveProfile()
+	if err != nil {
+		return err
 	}
+	scopes := []string{DefaultScope}
 	if len(cf.Scopes) > 0 {
 		scopes = cf.Scopes
 	}
-	if cf.Timeout > 0 {
-		timeout = cf.Timeout
-	}
 	conf := &cc.Config{
 		ClientID:     cf.ClientId,
 		ClientSecret: cf.ClientSecret,
 		Scopes:       scopes,
-		TokenURL:     baseUrl + "/oauth2/token",
+		TokenURL:     profile.TokenURL,
+	}
+	ctx, err := newHTTPContext(cf, baseUrls)
+	if err != nil {
+		return err
+	}
+	metrics := cf.Metrics
+	if metrics == nil {
+		metrics = nopMetrics{}
+	}
+	// oauth2.NewClient 不传 token 时返回 ctx 中的底层 client，在它的 Transport 之上附加 token 或签名
+	base := oauth2.NewClient(ctx, nil).Transport
+	var transport http.RoundTripper
+	var tokens *tokenSource
+	if cf.Signature.Enabled() {
+		transport = newSignatureTransport(cf.Signature, base)
+	} else {
+		tokens = newTokenSource(ctx, conf)
+		tokens.metrics = metrics
+		if cf.Tracer != nil {
+			tokens.tracer = cf.Tracer
+		}
+		transport = &oauth2.Transport{Source: tokens, Base: base}
 	}
-	client := conf.Client(context.Background())
-	client.Timeout = time.Second * time.Duration(timeout)
+	if cf.Metrics != nil {
+		transport = &metricsTransport{metrics: metrics, next: transport}
+	}
+	if cf.Tracer != nil {
+		// 放在最外层，traceparent 和 X-Ca-Request-Id 对应每一次实际发出的请求
+		transport = &tracingTransport{tracer: cf.Tracer, next: transport}
+	}
+	client := &http.Client{Transport: transport, Timeout: cf.requestTimeout()}
 
-	openAPIClient = &OAuth2Client{Client: client, BaseUrl: baseUrl, Debug: cf.Debug}
+	lock.Lock()
+	defer lock.Unlock()
+	openAPIClient = &OAuth2Client{tokens: tokens, metrics: cf.Metrics, tracer: cf.Tracer, Client: client, BaseUrl: profile.BaseUrl, Debug: cf.Debug}
+	return nil
+}
+
+// newHTTPContext 构造获取 token 和调用接口共用的 http.Client，通过 context 传递给 oauth2
+func newHTTPContext(cf OAuth2Config, baseUrls []string) (context.Context, error) {
+	transport, err := cf.Transport.newBaseTransport()
+	if err != nil {
+		return nil, err
+	}
+	if len(baseUrls) > 1 {
+		if transport, err = newFailoverTransport(baseUrls, cf.FailoverCooldown, transport); err != nil {
+			return nil, err
+		}
+	}
+	if transport, err = NewCassetteTransport(cf.Cassette, transport); err != nil {
+		return nil, err
+	}
+	scopes := cf.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{DefaultScope}
+	}
+	transport = newCacheTransport(cf.ResponseCache, scopes, transport)
+	// 获取 token 时直接使用这个 client，调用接口时 oauth2 会在它的 Transport 之上附加 token
+	base := &http.Client{Transport: transport, Timeout: cf.requestTimeout()}
+	return context.WithValue(context.Background(), oauth2.HTTPClient, base), nil
 }
 
 // GetOpenAPIClient 获取接口的Client信息
@@ -92,15 +199,3 @@ func GetOpenAPIClient() *OAuth2Client {
 	defer lock.RUnlock()
 	return openAPIClient
 }
-
-func (c *OAuth2Client) retryAdd() {
-	lock.RLock()
-	defer lock.RUnlock()
-	c.RetryCount = c.RetryCount + 1
-}
-
-func (c *OAuth2Client) retryRest() {
-	lock.RLock()
-	defer lock.RUnlock()
-	c.RetryCount = 0
-}
@@ -0,0 +1,91 @@
+package sdk
+
+import "context"
+
+/*
+Iterator 逐条读取分页接口的数据，当前页读完后自动请求下一页，返回空页时结束
+
+	it := sdk.NewIterator(func(ctx context.Context, pageNum int) ([]Organization, error) {
+		var page struct {
+			Rows []Organization `json:"rows"`
+		}
+		_, err := c.R().Path(apiPath).Query("pageNum", pageNum).Query("pageSize", 100).Into(&page).Do(ctx)
+		return page.Rows, err
+	})
+	for it.Next(ctx) {
+		org := it.Value()
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+ecnu-gen 为分页接口生成的 XxxIterator 方法返回的就是 Iterator。
+fetch 收到的 ctx 标记为分页请求，通过 Do(ctx) 发出的请求只在 ResponseCacheConfig.PathTTL 中设置了路径时缓存。
+*/
+type Iterator[T any] struct {
+	fetch   func(ctx context.Context, pageNum int) ([]T, error)
+	pageNum int
+	rows    []T
+	index   int
+	current T
+	done    bool
+	err     error
+}
+
+// NewIterator 创建 Iterator，fetch 按页码返回一页数据，页码从 1 开始
+func NewIterator[T any](fetch func(ctx context.Context, pageNum int) ([]T, error)) *Iterator[T] {
+	return &Iterator[T]{fetch: fetch}
+}
+
+// Next 移动到下一条数据，没有更多数据或出错时返回 false
+func (it *Iterator[T]) Next(ctx context.Context) bool {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	for it.err == nil && !it.done {
+		if it.index < len(it.rows) {
+			it.current = it.rows[it.index]
+			it.index++
+			return true
+		}
+		it.pageNum++
+		it.rows, it.err = it.fetch(withPageRequest(ctx), it.pageNum)
+		it.index = 0
+		if it.err == nil && len(it.rows) == 0 {
+			it.done = true
+		}
+	}
+	return false
+}
+
+// Value 返回当前数据
+func (it *Iterator[T]) Value() T {
+	return it.current
+}
+
+// PageNum 返回当前数据所在的页码
+func (it *Iterator[T]) PageNum() int {
+	return it.pageNum
+}
+
+// Err 返回读取过程中的错误
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// All 读取剩余的全部数据
+func (it *Iterator[T]) All(ctx context.Context) ([]T, error) {
+	var rows []T
+	for it.Next(ctx) {
+		rows = append(rows, it.Value())
+	}
+	return rows, it.Err()
+}
+
+// Page 分页接口中一页的数据，与 DataResult 相同，Rows 为具体的类型
+type Page[T any] struct {
+	TotalNum int `json:"totalNum"`
+	PageSize int `json:"pageSize"`
+	PageNum  int `json:"pageNum"`
+	Rows     []T `json:"rows"`
+}
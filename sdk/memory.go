@@ -0,0 +1,189 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+/*
+MemoryClient 不发出 http 请求的 Requester 实现，从内存中返回预置的数据，用于单元测试
+
+	mc := sdk.NewMemoryClient()
+	mc.AddPages("/api/v1/sync/fakewithts", page1, page2)
+	res, err := mc.SyncToDB(db, api, &rows)
+*/
+type MemoryClient struct {
+	mu     sync.Mutex
+	pages  map[string][][]interface{}
+	rows   map[string][]interface{}
+	data   map[string]json.RawMessage
+	errors map[string]error
+	calls  []string
+}
+
+var (
+	_ Requester = (*MemoryClient)(nil)
+	_ Syncer    = (*MemoryClient)(nil)
+)
+
+func NewMemoryClient() *MemoryClient {
+	return &MemoryClient{
+		pages:  make(map[string][][]interface{}),
+		rows:   make(map[string][]interface{}),
+		data:   make(map[string]json.RawMessage),
+		errors: make(map[string]error),
+	}
+}
+
+// AddPages 按顺序预置每一页的数据，第 n 页之后返回空页，忽略 pageSize
+func (m *MemoryClient) AddPages(apiPath string, pages ...[]interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pages[apiPath] = append(m.pages[apiPath], pages...)
+}
+
+// AddRows 预置全部行数据，按请求的 pageNum、pageSize 分页返回
+func (m *MemoryClient) AddRows(apiPath string, rows []interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rows[apiPath] = append(m.rows[apiPath], rows...)
+}
+
+// AddData 预置非翻页接口返回的 data
+func (m *MemoryClient) AddData(apiPath string, data interface{}) error {
+	content, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[apiPath] = content
+	return nil
+}
+
+// SetError 使某个接口返回错误
+func (m *MemoryClient) SetError(apiPath string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors[apiPath] = err
+}
+
+// Calls 返回已经发生的调用，格式为 "METHOD path?query"
+func (m *MemoryClient) Calls() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.calls...)
+}
+
+func (m *MemoryClient) HttpGet(url string) (json.RawMessage, error) {
+	return m.HttpRequest(url, "GET", nil, nil)
+}
+
+func (m *MemoryClient) HttpRequest(rawURL, method string, header map[string]string, body io.Reader) (json.RawMessage, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	m.record(strings.ToUpper(method), u.RequestURI())
+
+	m.mu.Lock()
+	data, isData := m.data[u.Path]
+	err = m.errors[u.Path]
+	m.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if isData {
+		return data, nil
+	}
+
+	query := u.Query()
+	pageNum, _ := strconv.Atoi(query.Get("pageNum"))
+	pageSize, _ := strconv.Atoi(query.Get("pageSize"))
+	result, err := m.page(u.Path, pageNum, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(result)
+}
+
+func (m *MemoryClient) GetRows(apiPath string, pageNum, pageSize int) (DataResult, error) {
+	path, _, _ := strings.Cut(apiPath, "?")
+	m.record("GET", fmt.Sprintf("%s pageNum=%d pageSize=%d", apiPath, pageNum, pageSize))
+
+	m.mu.Lock()
+	err := m.errors[path]
+	m.mu.Unlock()
+	if err != nil {
+		return DataResult{}, err
+	}
+	return m.page(path, pageNum, pageSize)
+}
+
+func (m *MemoryClient) page(path string, pageNum, pageSize int) (DataResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if pageNum < 1 {
+		pageNum = 1
+	}
+	result := DataResult{PageNum: pageNum, PageSize: pageSize, Rows: []interface{}{}}
+
+	if pages, ok := m.pages[path]; ok {
+		for _, page := range pages {
+			result.TotalNum += len(page)
+		}
+		if pageNum <= len(pages) {
+			result.Rows = pages[pageNum-1]
+		}
+		return result, nil
+	}
+	if rows, ok := m.rows[path]; ok {
+		if pageSize < 1 {
+			pageSize = len(rows)
+		}
+		result.TotalNum = len(rows)
+		start := (pageNum - 1) * pageSize
+		if start < len(rows) {
+			end := start + pageSize
+			if end > len(rows) {
+				end = len(rows)
+			}
+			result.Rows = rows[start:end]
+		}
+		return result, nil
+	}
+	return result, fmt.Errorf("memory client: api %s not found", path)
+}
+
+func (m *MemoryClient) record(method, uri string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, method+" "+uri)
+}
+
+func (m *MemoryClient) SyncToFile(mode string, fileName string, api APIConfig) (SyncResult, error) {
+	return syncToFile(m, mode, fileName, api)
+}
+
+func (m *MemoryClient) SyncToModel(api APIConfig, dataModel interface{}) (SyncResult, error) {
+	return syncToModel(m, api, dataModel)
+}
+
+func (m *MemoryClient) SyncToDB(db *gorm.DB, api APIConfig, dataModel interface{}) (SyncResult, error) {
+	return syncToDB(m, db, api, dataModel)
+}
+
+func (m *MemoryClient) SyncIncrementalToDB(db *gorm.DB, api APIConfig, dataModel interface{}, opts IncrementalOptions) (SyncResult, error) {
+	return syncIncrementalToDB(m, db, api, dataModel, opts)
+}
+
+func (m *MemoryClient) SyncFullRefreshToDB(db *gorm.DB, api APIConfig, dataModel interface{}) (SyncResult, error) {
+	return syncFullRefreshToDB(m, db, api, dataModel)
+}
@@ -0,0 +1,59 @@
+package sdk
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_MemoryClientSyncToDB(t *testing.T) {
+	mc := NewMemoryClient()
+	rows := newTestRows(5, "2023-01-02 00:00:00")
+	mc.AddPages(testAPIPath,
+		[]interface{}{rows[0], rows[1], rows[2]},
+		[]interface{}{rows[3], rows[4]},
+	)
+
+	var s Syncer = mc
+	res, err := s.SyncToDB(newTestDB(t), APIConfig{APIPath: testAPIPath}, &[]testFakeRow{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Rows != 5 {
+		t.Errorf("sync should get 5 rows, got %d", res.Rows)
+	}
+	// 两页数据加一次空页
+	if len(mc.Calls()) != 3 {
+		t.Errorf("unexpected calls: %v", mc.Calls())
+	}
+}
+
+func Test_MemoryClientRequester(t *testing.T) {
+	mc := NewMemoryClient()
+	var r Requester = mc
+	mc.AddRows(testAPIPath, []interface{}{map[string]interface{}{"id": 1}, map[string]interface{}{"id": 2}})
+	if err := mc.AddData("/api/v1/organization/list", []string{"0445"}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := r.GetRows(testAPIPath+"?ts=0", 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.TotalNum != 2 || len(res.Rows) != 1 {
+		t.Errorf("unexpected page: %+v", res)
+	}
+
+	data, err := r.HttpGet("https://api.ecnu.edu.cn/api/v1/organization/list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `["0445"]` {
+		t.Errorf("unexpected data: %s", data)
+	}
+
+	mc.SetError(testAPIPath, errors.New("A403IP"))
+	var rows []testFakeRow
+	if _, err := NewSyncer(r).SyncToModel(APIConfig{APIPath: testAPIPath}, &rows); err == nil {
+		t.Error("error should be returned")
+	}
+}
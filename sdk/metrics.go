@@ -0,0 +1,72 @@
+package sdk
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Metrics 接口调用和数据同步的指标，实现需要并发安全
+type Metrics interface {
+	// ObserveAPICall 记录一次接口调用，errCode 为 X-Ca-Error-Code 响应头，status 为 0 表示网络错误
+	ObserveAPICall(path string, status int, errCode string, duration time.Duration)
+	// ObserveTokenRefresh 记录一次获取 token
+	ObserveTokenRefresh(duration time.Duration, err error)
+	// ObserveSync 记录一次数据同步
+	ObserveSync(s SyncMetrics)
+}
+
+// SyncMetrics 一次数据同步的统计
+type SyncMetrics struct {
+	APIPath string
+	// Target 同步目标：db、model、csv、xlsx
+	Target  string
+	Pages   int64
+	Rows    int64
+	Batches int64
+	// Deleted 按删除标记或删除对账删除的行数
+	Deleted  int64
+	Duration time.Duration
+	Err      error
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) ObserveAPICall(string, int, string, time.Duration) {}
+func (nopMetrics) ObserveTokenRefresh(time.Duration, error)          {}
+func (nopMetrics) ObserveSync(SyncMetrics)                           {}
+
+// metricsOf 返回 requester 上配置的 Metrics，APIConfig 中的配置优先
+func metricsOf(r Requester, api APIConfig) Metrics {
+	if api.Metrics != nil {
+		return api.Metrics
+	}
+	if c, ok := r.(*OAuth2Client); ok && c.metrics != nil {
+		return c.metrics
+	}
+	return nopMetrics{}
+}
+
+// metricsTransport 记录每一次接口调用的状态码、错误码和耗时
+type metricsTransport struct {
+	metrics Metrics
+	next    http.RoundTripper
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		t.metrics.ObserveAPICall(req.URL.Path, 0, "", time.Since(start))
+		return resp, err
+	}
+	t.metrics.ObserveAPICall(req.URL.Path, resp.StatusCode, resp.Header.Get("X-Ca-Error-Code"), time.Since(start))
+	return resp, nil
+}
+
+func statusLabel(status int) string {
+	if status == 0 {
+		return "error"
+	}
+	return strconv.Itoa(status)
+}
@@ -0,0 +1,224 @@
+package sdk
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDurationBuckets 耗时直方图的默认分桶，单位秒
+var DefaultDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300}
+
+/*
+ExpvarMetrics 基于 expvar 的 Metrics 实现，不依赖第三方库
+
+	m := sdk.NewExpvarMetrics("ecnu_openapi")
+	cf.Metrics = m
+	http.Handle("/metrics", m.PrometheusHandler())
+
+指标通过 expvar 发布（默认挂载在 /debug/vars），也可以通过 PrometheusHandler 以 Prometheus 文本格式输出。
+*/
+type ExpvarMetrics struct {
+	buckets []float64
+
+	mu         sync.Mutex
+	counters   map[string]*metricSeries
+	histograms map[string]*metricSeries
+}
+
+type metricSeries struct {
+	name   string
+	labels []string
+	value  float64
+	// 直方图使用
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// 已经发布到 expvar 的 ExpvarMetrics，expvar 不允许重复发布同一个名称
+var (
+	expvarMetrics     = map[string]*ExpvarMetrics{}
+	expvarMetricsLock = new(sync.Mutex)
+)
+
+/*
+NewExpvarMetrics 创建并以 name 发布到 expvar，name 为空时不发布
+
+同一个 name 多次调用（例如重新初始化 client）时返回之前创建的实例，指标继续累加；
+name 已经被其他 expvar 变量占用时通过 log 输出提示，不发布。
+*/
+func NewExpvarMetrics(name string) *ExpvarMetrics {
+	expvarMetricsLock.Lock()
+	defer expvarMetricsLock.Unlock()
+	if m, ok := expvarMetrics[name]; ok {
+		return m
+	}
+	m := &ExpvarMetrics{
+		buckets:    DefaultDurationBuckets,
+		counters:   make(map[string]*metricSeries),
+		histograms: make(map[string]*metricSeries),
+	}
+	if name == "" {
+		return m
+	}
+	if expvar.Get(name) != nil {
+		log.Printf("expvar %s 已经存在，指标不会发布到 expvar", name)
+		return m
+	}
+	expvar.Publish(name, expvar.Func(m.snapshot))
+	expvarMetrics[name] = m
+	return m
+}
+
+func (m *ExpvarMetrics) ObserveAPICall(path string, status int, errCode string, duration time.Duration) {
+	m.add("ecnu_api_calls_total", 1, "path", path, "status", statusLabel(status), "code", errCode)
+	m.observe("ecnu_api_call_duration_seconds", duration.Seconds(), "path", path, "status", statusLabel(status), "code", errCode)
+}
+
+func (m *ExpvarMetrics) ObserveTokenRefresh(duration time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	m.add("ecnu_token_refresh_total", 1, "result", result)
+	m.observe("ecnu_token_refresh_duration_seconds", duration.Seconds())
+}
+
+func (m *ExpvarMetrics) ObserveSync(s SyncMetrics) {
+	result := "success"
+	if s.Err != nil {
+		result = "error"
+	}
+	m.add("ecnu_sync_runs_total", 1, "api", s.APIPath, "target", s.Target, "result", result)
+	m.add("ecnu_sync_pages_total", float64(s.Pages), "api", s.APIPath, "target", s.Target)
+	m.add("ecnu_sync_rows_total", float64(s.Rows), "api", s.APIPath, "target", s.Target)
+	m.add("ecnu_sync_batches_total", float64(s.Batches), "api", s.APIPath, "target", s.Target)
+	m.add("ecnu_sync_deleted_total", float64(s.Deleted), "api", s.APIPath, "target", s.Target)
+	m.observe("ecnu_sync_duration_seconds", s.Duration.Seconds(), "api", s.APIPath, "target", s.Target)
+}
+
+// Counter 返回计数器的当前值，labels 为 key、value 交替排列，用于测试或自定义输出
+func (m *ExpvarMetrics) Counter(name string, labels ...string) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.counters[seriesKey(name, labels)]; ok {
+		return s.value
+	}
+	return 0
+}
+
+func (m *ExpvarMetrics) add(name string, value float64, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := seriesKey(name, labels)
+	s, ok := m.counters[key]
+	if !ok {
+		s = &metricSeries{name: name, labels: labels}
+		m.counters[key] = s
+	}
+	s.value += value
+}
+
+func (m *ExpvarMetrics) observe(name string, value float64, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := seriesKey(name, labels)
+	s, ok := m.histograms[key]
+	if !ok {
+		s = &metricSeries{name: name, labels: labels, counts: make([]uint64, len(m.buckets))}
+		m.histograms[key] = s
+	}
+	for i, upper := range m.buckets {
+		if value <= upper {
+			s.counts[i]++
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+// snapshot 供 expvar 输出的 json 结构
+func (m *ExpvarMetrics) snapshot() interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := make(map[string]interface{})
+	for key, s := range m.counters {
+		res[key] = s.value
+	}
+	for key, s := range m.histograms {
+		res[key] = map[string]interface{}{"count": s.count, "sum": s.sum}
+	}
+	return res
+}
+
+// PrometheusHandler 以 Prometheus 文本格式输出全部指标
+func (m *ExpvarMetrics) PrometheusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.writePrometheus(w)
+	})
+}
+
+func (m *ExpvarMetrics) writePrometheus(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var sb strings.Builder
+	typed := make(map[string]bool)
+	for _, key := range sortedKeys(m.counters) {
+		s := m.counters[key]
+		if !typed[s.name] {
+			fmt.Fprintf(&sb, "# TYPE %s counter\n", s.name)
+			typed[s.name] = true
+		}
+		fmt.Fprintf(&sb, "%s%s %g\n", s.name, formatLabels(s.labels, "", ""), s.value)
+	}
+	for _, key := range sortedKeys(m.histograms) {
+		s := m.histograms[key]
+		if !typed[s.name] {
+			fmt.Fprintf(&sb, "# TYPE %s histogram\n", s.name)
+			typed[s.name] = true
+		}
+		for i, upper := range m.buckets {
+			fmt.Fprintf(&sb, "%s_bucket%s %d\n", s.name, formatLabels(s.labels, "le", fmt.Sprintf("%g", upper)), s.counts[i])
+		}
+		fmt.Fprintf(&sb, "%s_bucket%s %d\n", s.name, formatLabels(s.labels, "le", "+Inf"), s.count)
+		fmt.Fprintf(&sb, "%s_sum%s %g\n", s.name, formatLabels(s.labels, "", ""), s.sum)
+		fmt.Fprintf(&sb, "%s_count%s %d\n", s.name, formatLabels(s.labels, "", ""), s.count)
+	}
+	_, _ = w.Write([]byte(sb.String()))
+}
+
+func seriesKey(name string, labels []string) string {
+	return name + formatLabels(labels, "", "")
+}
+
+func formatLabels(labels []string, extraKey, extraValue string) string {
+	var parts []string
+	for i := 0; i+1 < len(labels); i += 2 {
+		parts = append(parts, fmt.Sprintf("%s=%q", labels[i], labels[i+1]))
+	}
+	if extraKey != "" {
+		parts = append(parts, fmt.Sprintf("%s=%q", extraKey, extraValue))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func sortedKeys(m map[string]*metricSeries) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
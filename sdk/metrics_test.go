@@ -0,0 +1,110 @@
+package sdk
+
+import (
+	"expvar"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk/sdktest"
+)
+
+func Test_Metrics(t *testing.T) {
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	if _, err := srv.AddRows(testAPIPath, newTestRows(25, "2023-01-02 00:00:00")); err != nil {
+		t.Fatal(err)
+	}
+	m := NewExpvarMetrics("")
+	err := SetupOAuth2ClientCredentials(OAuth2Config{
+		ClientId:     srv.ClientId,
+		ClientSecret: srv.ClientSecret,
+		BaseUrl:      srv.URL,
+		Metrics:      m,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.InjectFault(sdktest.InvalidToken(testAPIPath, 1))
+
+	api := APIConfig{APIPath: testAPIPath, PageSize: 10, BatchSize: 4}
+	rows := []testFakeRow{}
+	res, err := SyncToDB(newTestDB(t), api, &rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Rows != 25 {
+		t.Fatalf("expected 25 rows, got %d", res.Rows)
+	}
+
+	if n := m.Counter("ecnu_token_refresh_total", "result", "success"); n != 2 {
+		t.Errorf("token should be fetched twice, got %v", n)
+	}
+	if n := m.Counter("ecnu_api_calls_total", "path", testAPIPath, "status", "200", "code", ""); n != 4 {
+		t.Errorf("expected 4 successful api calls, got %v", n)
+	}
+	if n := m.Counter("ecnu_api_calls_total", "path", testAPIPath, "status", "401", "code", "A401OT"); n != 1 {
+		t.Errorf("expected 1 A401OT api call, got %v", n)
+	}
+	sync := []string{"api", testAPIPath, "target", "db"}
+	if n := m.Counter("ecnu_sync_runs_total", append(sync, "result", "success")...); n != 1 {
+		t.Errorf("expected 1 sync run, got %v", n)
+	}
+	if n := m.Counter("ecnu_sync_pages_total", sync...); n != 4 {
+		t.Errorf("expected 4 pages, got %v", n)
+	}
+	if n := m.Counter("ecnu_sync_rows_total", sync...); n != 25 {
+		t.Errorf("expected 25 rows, got %v", n)
+	}
+	// 每页 10、10、5 行，批次大小 4
+	if n := m.Counter("ecnu_sync_batches_total", sync...); n != 8 {
+		t.Errorf("expected 8 batches, got %v", n)
+	}
+
+	rec := httptest.NewRecorder()
+	m.PrometheusHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	for _, want := range []string{
+		"# TYPE ecnu_api_calls_total counter",
+		`ecnu_sync_rows_total{api="` + testAPIPath + `",target="db"} 25`,
+		"# TYPE ecnu_sync_duration_seconds histogram",
+		`ecnu_sync_duration_seconds_count{api="` + testAPIPath + `",target="db"} 1`,
+		`ecnu_api_call_duration_seconds_count{path="` + testAPIPath + `",status="401",code="A401OT"} 1`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("prometheus output should contain %q", want)
+		}
+	}
+}
+
+func Test_MetricsPerAPIConfig(t *testing.T) {
+	mc := NewMemoryClient()
+	for _, row := range newTestRows(3, "2023-01-02 00:00:00") {
+		mc.AddRows(testAPIPath, []interface{}{row})
+	}
+	m := NewExpvarMetrics("")
+	api := APIConfig{APIPath: testAPIPath, PageSize: 2, Metrics: m}
+	rows := []testFakeRow{}
+	if _, err := mc.SyncToModel(api, &rows); err != nil {
+		t.Fatal(err)
+	}
+	if n := m.Counter("ecnu_sync_rows_total", "api", testAPIPath, "target", "model"); n != 3 {
+		t.Errorf("expected 3 rows, got %v", n)
+	}
+}
+
+func Test_ExpvarMetricsName(t *testing.T) {
+	m := NewExpvarMetrics("ecnu_openapi_test")
+	if NewExpvarMetrics("ecnu_openapi_test") != m {
+		t.Error("same name should return the published metrics")
+	}
+	if NewExpvarMetrics("") == NewExpvarMetrics("") {
+		t.Error("unnamed metrics should not be shared")
+	}
+	// 名称已经被其他 expvar 变量占用时不会 panic
+	expvar.NewInt("ecnu_openapi_test_int")
+	if NewExpvarMetrics("ecnu_openapi_test_int") == nil {
+		t.Error("expected metrics")
+	}
+}
@@ -0,0 +1,93 @@
+package sdk
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// 同步前数据表结构的处理方式，对应 APIConfig.Migrate
+const (
+	// MigrateAuto 默认，使用 gorm 的 AutoMigrate 创建表、添加缺少的列和索引
+	MigrateAuto = "auto"
+	// MigrateCreate 只在表不存在时创建，已经存在的表不做任何修改
+	MigrateCreate = "create"
+	// MigrateVerify 不修改数据库，表不存在或者列与模型不一致时返回错误
+	MigrateVerify = "verify"
+	// MigrateOff 不检查也不修改数据库
+	MigrateOff = "off"
+)
+
+// tableDB 设置了 APIConfig.Table 时使用指定的表名，返回可以复用的会话，避免条件互相影响
+func (api APIConfig) tableDB(db *gorm.DB) *gorm.DB {
+	if api.Table != "" {
+		db = db.Table(api.Table)
+	}
+	return db.Session(&gorm.Session{})
+}
+
+// migrate 按 APIConfig.Migrate 处理数据表结构
+func migrate(db *gorm.DB, api APIConfig, dataModel interface{}) error {
+	switch api.Migrate {
+	case "", MigrateAuto:
+		return db.AutoMigrate(dataModel)
+	case MigrateCreate:
+		if db.Migrator().HasTable(dataModel) {
+			return nil
+		}
+		if err := db.Migrator().CreateTable(dataModel); err != nil {
+			return fmt.Errorf("create table fail: %v", err)
+		}
+		return nil
+	case MigrateVerify:
+		return verifyTable(db, dataModel)
+	case MigrateOff:
+		return nil
+	}
+	return fmt.Errorf("unknown migrate mode: %s", api.Migrate)
+}
+
+// verifyTable 比较模型与数据表的列，不一致时返回缺少和多出的列
+func verifyTable(db *gorm.DB, dataModel interface{}) error {
+	s, err := parseModel(db, dataModel)
+	if err != nil {
+		return err
+	}
+	table := db.Statement.Table
+	if table == "" {
+		table = s.Table
+	}
+	if !db.Migrator().HasTable(dataModel) {
+		return fmt.Errorf("verify table %s fail: table not exists", table)
+	}
+	columnTypes, err := db.Migrator().ColumnTypes(dataModel)
+	if err != nil {
+		return fmt.Errorf("verify table %s fail: %v", table, err)
+	}
+	columns := make(map[string]bool, len(columnTypes))
+	for _, c := range columnTypes {
+		columns[strings.ToLower(c.Name())] = true
+	}
+
+	var missing, extra []string
+	fields := make(map[string]bool, len(s.DBNames))
+	for _, name := range s.DBNames {
+		fields[strings.ToLower(name)] = true
+		if !columns[strings.ToLower(name)] {
+			missing = append(missing, name)
+		}
+	}
+	for _, c := range columnTypes {
+		if !fields[strings.ToLower(c.Name())] {
+			extra = append(extra, c.Name())
+		}
+	}
+	if len(missing) == 0 && len(extra) == 0 {
+		return nil
+	}
+	sort.Strings(extra)
+	return fmt.Errorf("verify table %s fail: missing columns [%s], extra columns [%s]",
+		table, strings.Join(missing, ", "), strings.Join(extra, ", "))
+}
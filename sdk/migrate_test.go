@@ -0,0 +1,93 @@
+package sdk
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// testNarrowRow 与 testFakeRow 使用同一张表，但是少了 name 列
+type testNarrowRow struct {
+	Id          int       `json:"id" gorm:"primarykey;autoIncrement:false"`
+	UpdateTime  time.Time `json:"updated_at" gorm:"column:updated_at"`
+	DeletedMark int       `json:"deleted_mark"`
+}
+
+func (testNarrowRow) TableName() string {
+	return "test_fake_rows"
+}
+
+func Test_SyncMigrate(t *testing.T) {
+	mc := newTestClient(newTestRows(3, "2023-01-02 00:00:00"))
+	db := newTestDB(t)
+	api := APIConfig{APIPath: testAPIPath}
+
+	// 表不存在时
+	for mode, want := range map[string]string{MigrateVerify: "table not exists", MigrateOff: "no such table", "unknown": "unknown migrate mode"} {
+		api.Migrate = mode
+		if _, err := mc.SyncToDB(db, api, &[]testFakeRow{}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected %q, got %v", mode, want, err)
+		}
+	}
+
+	// 已经存在的表不会添加缺少的列
+	if err := db.Migrator().CreateTable(&testNarrowRow{}); err != nil {
+		t.Fatal(err)
+	}
+	api.Migrate = MigrateCreate
+	if _, err := mc.SyncToDB(db, api, &[]testFakeRow{}); err == nil || !strings.Contains(err.Error(), "name") {
+		t.Errorf("expected missing column error, got %v", err)
+	}
+	if db.Migrator().HasColumn(&testFakeRow{}, "name") {
+		t.Error("create mode should not alter existing table")
+	}
+
+	api.Migrate = MigrateVerify
+	if _, err := mc.SyncToDB(db, api, &[]testFakeRow{}); err == nil ||
+		!strings.Contains(err.Error(), "missing columns [name], extra columns []") {
+		t.Errorf("expected column diff, got %v", err)
+	}
+
+	api.Migrate = MigrateAuto
+	if _, err := mc.SyncToDB(db, api, &[]testFakeRow{}); err != nil {
+		t.Fatal(err)
+	}
+	api.Migrate = MigrateVerify
+	if _, err := mc.SyncToDB(db, api, &[]testFakeRow{}); err != nil {
+		t.Errorf("expected verified table, got %v", err)
+	}
+	if _, err := mc.SyncToDB(db, api, &[]testNarrowRow{}); err == nil || !strings.Contains(err.Error(), "extra columns [name]") {
+		t.Errorf("expected column diff, got %v", err)
+	}
+}
+
+func Test_SyncTable(t *testing.T) {
+	db := newTestDB(t)
+	for i, table := range []string{"fake_rows_a", "fake_rows_b"} {
+		api := APIConfig{APIPath: testAPIPath, Table: table, Migrate: MigrateCreate}
+		if _, err := newTestClient(newTestRows(i+1, "2023-01-02 00:00:00")).SyncIncrementalToDB(db, api, &[]testFakeRow{}, IncrementalOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		var total int64
+		db.Table(table).Count(&total)
+		if total != int64(i+1) {
+			t.Errorf("%s: expected %d rows, got %d", table, i+1, total)
+		}
+	}
+	if db.Migrator().HasTable(&testFakeRow{}) {
+		t.Error("model table should not be created")
+	}
+	if ts := GetLastUpdatedTS(db, APIConfig{Table: "fake_rows_a"}, &[]testFakeRow{}); ts == 0 {
+		t.Error("last updated ts should be read from the table override")
+	}
+
+	api := APIConfig{APIPath: testAPIPath, Table: "fake_rows_a", Migrate: MigrateVerify}
+	if _, err := newTestClient(newTestRows(4, "2023-01-02 00:00:00")).SyncFullRefreshToDB(db, api, &[]testFakeRow{}); err != nil {
+		t.Fatal(err)
+	}
+	var total int64
+	db.Table("fake_rows_a").Count(&total)
+	if total != 4 {
+		t.Errorf("expected 4 rows, got %d", total)
+	}
+}
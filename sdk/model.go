@@ -1,16 +1,21 @@
 package sdk
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 )
 
 const (
 	MAXPageSIZE = 10000
+
+	// maxTokenRetry 网关返回 A401OT 时，单个请求最多重新获取 token 的次数
+	maxTokenRetry = 3
 )
 
 /*
@@ -31,20 +36,18 @@ func parseApiResult(result *http.Response, debug bool) (APIResult, error) {
 		fmt.Println(result.StatusCode)
 		fmt.Println(result.Header)
 	}
-	if result.StatusCode != 200 {
-		//错误码：A401OT access_token 参数错误。清空 access_token 再来一次
-		if result.Header.Get("X-Ca-Error-Code") == "A401OT" {
-			return data, errors.New("A401OT")
-		}
-		return data, fmt.Errorf("invoke api get fail, X-Ca-Error-Code: %s, X-Ca-Error-Message: %s,X-Ca-Request-Id: %s",
-			result.Header.Get("X-Ca-Error-Code"),
-			result.Header.Get("X-Ca-Error-Message"),
-			result.Header.Get("X-Ca-Request-Id"))
-	}
-
 	if result.Body == nil {
 		return data, fmt.Errorf("get api response body is nil")
 	}
+	if result.StatusCode != 200 {
+		result.Body.Close()
+		return data, &GatewayError{
+			StatusCode: result.StatusCode,
+			Code:       result.Header.Get("X-Ca-Error-Code"),
+			Message:    result.Header.Get("X-Ca-Error-Message"),
+			RequestId:  result.Header.Get("X-Ca-Request-Id"),
+		}
+	}
 
 	defer result.Body.Close()
 	res, err := io.ReadAll(result.Body)
@@ -63,45 +66,115 @@ func parseApiResult(result *http.Response, debug bool) (APIResult, error) {
 
 // HttpGet 通用GET请求
 func (c *OAuth2Client) HttpGet(url string) (json.RawMessage, error) {
-	var apiResult APIResult
-	var err error
+	return c.httpRequest(context.Background(), url, http.MethodGet, nil, nil)
+}
 
-	result, err := c.Client.Get(url)
+// HttpRequest 通用 http 请求，body 会被完整读取，以便在 token 失效重试时重新发送
+func (c *OAuth2Client) HttpRequest(url, method string, header map[string]string, body io.Reader) (json.RawMessage, error) {
+	var content []byte
+	if body != nil {
+		var err error
+		if content, err = io.ReadAll(body); err != nil {
+			return nil, fmt.Errorf("read request body fail: %v", err)
+		}
+	}
+	return c.httpRequest(context.Background(), url, strings.ToUpper(method), header, content)
+}
+
+func (c *OAuth2Client) httpRequest(ctx context.Context, url, method string, header map[string]string, body []byte) (json.RawMessage, error) {
+	resp, err := c.do(ctx, url, method, header, body)
 	if err != nil {
-		return nil, fmt.Errorf("invoke api get fail: %v", err)
+		return nil, err
 	}
-	apiResult, err = parseApiResult(result, c.Debug)
+	return resp.Data, nil
+}
+
+// do 发送请求并解析 APIResult，出错时如果已经收到响应，也会返回 Response
+func (c *OAuth2Client) do(ctx context.Context, url, method string, header map[string]string, body []byte) (*Response, error) {
+	result, err := c.send(ctx, url, method, header, body)
 	if err != nil {
-		if err.Error() == "A401OT" && c.RetryCount <= 3 {
-			//错误码：A401OT access_token 参数错误。清空再来一次
-			c.retryAdd()
-			c.Client = c.conf.Client(context.Background())
-			return c.HttpGet(url)
-		}
 		return nil, err
 	}
-	if c.RetryCount > 0 {
-		//token 是正确的，如果之前有计数，这里要清零了
-		c.retryRest()
+	resp := &Response{
+		StatusCode: result.StatusCode,
+		Header:     result.Header,
+		RequestId:  result.Header.Get("X-Ca-Request-Id"),
+	}
+	var counter *countingReader
+	if result.Body != nil {
+		counter = &countingReader{ReadCloser: result.Body}
+		result.Body = counter
+	}
+	apiResult, err := parseApiResult(result, c.Debug)
+	if counter != nil {
+		resp.Size = counter.n
+	}
+	if err != nil {
+		return resp, err
 	}
+	if apiResult.RequestId != "" {
+		resp.RequestId = apiResult.RequestId
+	}
+	resp.ErrCode = apiResult.ErrCode
+	resp.ErrMsg = apiResult.ErrMsg
+	resp.Data = apiResult.Data
 	if apiResult.ErrCode != 0 {
-		return nil, errors.New(apiResult.ErrMsg)
+		return resp, &APIError{ErrCode: apiResult.ErrCode, ErrMsg: apiResult.ErrMsg, RequestId: resp.RequestId}
 	}
-
-	return apiResult.Data, nil
+	return resp, nil
 }
 
-// 通用 http 请求
-// todo
-func (c *OAuth2Client) HttpRequest(url, method string, header map[string]string, body io.Reader) (json.RawMessage, error) {
-	return nil, nil
+// send 发送请求，网关返回 A401OT 时重新获取 token 后重试，返回的响应体由调用方读取并关闭
+func (c *OAuth2Client) send(ctx context.Context, url, method string, header map[string]string, body []byte) (*http.Response, error) {
+	// 重试次数只属于本次请求，多个 goroutine 共用一个 client 时互不影响
+	for retry := 0; ; retry++ {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, url, reader)
+		if err != nil {
+			return nil, fmt.Errorf("create api request fail: %v", err)
+		}
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+
+		client := c.Client
+		if ctx.Value(noTimeoutKey{}) != nil && client.Timeout > 0 {
+			// 下载的超时由 ctx 控制
+			cp := *client
+			cp.Timeout = 0
+			client = &cp
+		}
+		result, err := client.Do(req)
+		if err != nil {
+			return nil, &RequestError{Method: method, Err: err}
+		}
+		expired := result.StatusCode != http.StatusOK && result.Header.Get("X-Ca-Error-Code") == "A401OT"
+		if expired && retry < maxTokenRetry && c.tokens != nil {
+			//错误码：A401OT access_token 参数错误。清空 access_token 再来一次
+			result.Body.Close()
+			c.tokens.invalidate(bearerToken(result.Request.Header.Get("Authorization")))
+			c.retryAdd()
+			continue
+		}
+		if !expired {
+			//token 是正确的，如果之前有计数，这里要清零了
+			c.retryReset()
+		}
+		return result, nil
+	}
 }
 
+// CallAPI 使用全局 client 调用接口，url 需要是完整的地址
 func CallAPI(url, method string, header map[string]string, body io.Reader) (json.RawMessage, error) {
 	c := GetOpenAPIClient()
-	switch method {
-	case "GET":
+	switch strings.ToUpper(method) {
+	case http.MethodGet:
 		return c.HttpGet(url)
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return c.HttpRequest(url, method, header, body)
 	}
 	return nil, errors.New("not support method")
 }
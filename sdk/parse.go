@@ -1,6 +1,7 @@
 package sdk
 
 import (
+	"bufio"
 	"encoding/csv"
 	"encoding/json"
 	"errors"
@@ -166,6 +167,31 @@ func parseRowsToCSV(rows []interface{}, filename string) error {
 	return nil
 }
 
+// parseRowsToJSONL 每行写入一个 json 对象
+func parseRowsToJSONL(rows []interface{}, filename string) error {
+	if len(rows) == 0 {
+		return errors.New("rows is empty")
+	}
+	if filename == "" {
+		return errors.New("filename is empty")
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	encoder.SetEscapeHTML(false)
+	for _, row := range rows {
+		if err := encoder.Encode(row); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
 // UnmarshalRows 将一个 []interface{} 的数据映射到一个 struct 数组
 func UnmarshalRows(src []interface{}, dst interface{}) error {
 	// 首先，检查dst是否是一个指向切片的指针
@@ -0,0 +1,143 @@
+package sdk
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// 内置的命名环境，其他环境通过 RegisterProfile 注册
+const (
+	ProfileProduction = "production"
+	ProfileTesting    = "testing"
+	ProfileCustom     = "custom"
+)
+
+// TestingBaseURLEnv 测试环境网关地址的环境变量，没有通过 RegisterProfile 注册 testing 时从这里读取
+const TestingBaseURLEnv = "ECNU_TESTING_BASE_URL"
+
+// Profile 一套完整的接口环境地址，两种授权模式都从这里读取 endpoint
+type Profile struct {
+	Name        string `json:"name"`
+	BaseUrl     string `json:"base_url"`
+	AuthURL     string `json:"auth_url"`
+	TokenURL    string `json:"token_url"`
+	UserInfoURL string `json:"user_info_url"`
+}
+
+var (
+	profiles = map[string]Profile{
+		ProfileProduction: {
+			Name:        ProfileProduction,
+			BaseUrl:     DefaultBaseURL,
+			AuthURL:     DefaultAuthURL,
+			TokenURL:    DefaultTokenURL,
+			UserInfoURL: DefaultUserInfoURL,
+		},
+	}
+	profileLock = new(sync.RWMutex)
+)
+
+// NewProfile 根据 baseUrl 推导出全部 endpoint
+func NewProfile(name, baseUrl string) Profile {
+	baseUrl = strings.TrimRight(baseUrl, "/")
+	return Profile{
+		Name:        name,
+		BaseUrl:     baseUrl,
+		AuthURL:     baseUrl + "/oauth2/authorize",
+		TokenURL:    baseUrl + "/oauth2/token",
+		UserInfoURL: baseUrl + "/oauth2/userinfo",
+	}
+}
+
+// RegisterProfile 注册或覆盖一个命名环境，也可以用来覆盖 testing 的地址
+func RegisterProfile(p Profile) {
+	if p.BaseUrl != "" {
+		derived := NewProfile(p.Name, p.BaseUrl)
+		if p.AuthURL == "" {
+			p.AuthURL = derived.AuthURL
+		}
+		if p.TokenURL == "" {
+			p.TokenURL = derived.TokenURL
+		}
+		if p.UserInfoURL == "" {
+			p.UserInfoURL = derived.UserInfoURL
+		}
+	}
+	profileLock.Lock()
+	defer profileLock.Unlock()
+	profiles[p.Name] = p
+}
+
+// GetProfile 获取已注册的命名环境，testing 没有注册时根据 TestingBaseURLEnv 推导
+func GetProfile(name string) (Profile, bool) {
+	profileLock.RLock()
+	defer profileLock.RUnlock()
+	p, ok := profiles[name]
+	if !ok && name == ProfileTesting {
+		if baseUrl := strings.TrimSpace(os.Getenv(TestingBaseURLEnv)); baseUrl != "" {
+			return NewProfile(name, baseUrl), true
+		}
+	}
+	return p, ok
+}
+
+/*
+resolveProfile 按以下优先级计算最终使用的 endpoint:
+ 1. Endpoint.AuthURL、Endpoint.TokenURL、UserInfoURL 显式配置的地址
+ 2. BaseUrls 的第一个地址或 BaseUrl 推导出的地址
+ 3. Profile 指定的命名环境，默认 production
+
+返回的 baseUrls 第一个是主地址，其余为故障切换的备用地址。
+*/
+func (cf OAuth2Config) resolveProfile() (Profile, []string, error) {
+	name := cf.Profile
+	if name == "" {
+		name = ProfileProduction
+	}
+
+	var p Profile
+	baseUrls := trimBaseUrls(cf.BaseUrls)
+	if cf.BaseUrl != "" && len(baseUrls) == 0 {
+		baseUrls = trimBaseUrls([]string{cf.BaseUrl})
+	}
+
+	if len(baseUrls) > 0 {
+		p = NewProfile(name, baseUrls[0])
+	} else {
+		registered, ok := GetProfile(name)
+		if !ok {
+			if name == ProfileCustom {
+				return p, nil, fmt.Errorf("profile %s requires base_url or base_urls", name)
+			}
+			if name == ProfileTesting {
+				return p, nil, fmt.Errorf("profile %s requires %s or RegisterProfile", name, TestingBaseURLEnv)
+			}
+			return p, nil, fmt.Errorf("profile %s is not registered", name)
+		}
+		p = registered
+		baseUrls = []string{p.BaseUrl}
+	}
+
+	if cf.Endpoint.AuthURL != "" {
+		p.AuthURL = cf.Endpoint.AuthURL
+	}
+	if cf.Endpoint.TokenURL != "" {
+		p.TokenURL = cf.Endpoint.TokenURL
+	}
+	if cf.UserInfoURL != "" {
+		p.UserInfoURL = cf.UserInfoURL
+	}
+	return p, baseUrls, nil
+}
+
+func trimBaseUrls(urls []string) []string {
+	var res []string
+	for _, u := range urls {
+		if u = strings.TrimRight(strings.TrimSpace(u), "/"); u != "" {
+			res = append(res, u)
+		}
+	}
+	return res
+}
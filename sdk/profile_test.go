@@ -0,0 +1,96 @@
+package sdk
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_ResolveProfile(t *testing.T) {
+	p, baseUrls, err := OAuth2Config{}.resolveProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.TokenURL != DefaultTokenURL || len(baseUrls) != 1 || baseUrls[0] != DefaultBaseURL {
+		t.Errorf("default profile should be production, got %+v %v", p, baseUrls)
+	}
+
+	p, _, err = OAuth2Config{BaseUrl: "https://gw.example.com/"}.resolveProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.TokenURL != "https://gw.example.com/oauth2/token" || p.UserInfoURL != "https://gw.example.com/oauth2/userinfo" {
+		t.Errorf("endpoints should be derived from base_url, got %+v", p)
+	}
+
+	p, _, err = OAuth2Config{Endpoint: EndpointConf{TokenURL: "https://sso.example.com/token"}}.resolveProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.TokenURL != "https://sso.example.com/token" || p.BaseUrl != DefaultBaseURL {
+		t.Errorf("explicit token_url should be honoured, got %+v", p)
+	}
+
+	if _, _, err = (OAuth2Config{Profile: "unknown"}).resolveProfile(); err == nil {
+		t.Error("unregistered profile should fail")
+	}
+	t.Setenv(TestingBaseURLEnv, "")
+	if _, _, err = (OAuth2Config{Profile: ProfileTesting}).resolveProfile(); err == nil || !strings.Contains(err.Error(), TestingBaseURLEnv) {
+		t.Errorf("testing profile without base url should fail, got %v", err)
+	}
+	t.Setenv(TestingBaseURLEnv, "https://test.example.com/")
+	p, _, err = OAuth2Config{Profile: ProfileTesting}.resolveProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.TokenURL != "https://test.example.com/oauth2/token" {
+		t.Errorf("testing profile should be derived from %s, got %+v", TestingBaseURLEnv, p)
+	}
+	RegisterProfile(Profile{Name: "staging", BaseUrl: "https://staging.example.com"})
+	p, _, err = OAuth2Config{Profile: "staging"}.resolveProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.AuthURL != "https://staging.example.com/oauth2/authorize" {
+		t.Errorf("registered profile endpoints should be derived, got %+v", p)
+	}
+}
+
+func Test_FailoverTransport(t *testing.T) {
+	var downHits, upHits int32
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&downHits, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&upHits, 1)
+		fmt.Fprint(w, r.URL.RequestURI())
+	}))
+	defer up.Close()
+
+	transport, err := newFailoverTransport([]string{down.URL, up.URL}, time.Minute, http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: transport}
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(down.URL + "/api/v1/list?pageNum=1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("request should fail over to the healthy node, got %d", resp.StatusCode)
+		}
+	}
+	// 主节点进入冷却期后，后续请求直接发往备用节点
+	if downHits != 1 || upHits != 3 {
+		t.Errorf("unexpected hits: down=%d up=%d", downHits, upHits)
+	}
+}
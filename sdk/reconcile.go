@@ -0,0 +1,247 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+/*
+ReconcileOptions 全量同步后的删除对账
+
+接口没有删除标记时，全量同步结束后，表中主键没有出现在本次同步数据中的行视为上游已删除：
+
+  - 设置 Column 时，将该列更新为 Value，Value 为 nil 时使用当前时间，列需要是模型中的字段
+  - 没有设置 Column、模型中有 gorm.DeletedAt 字段时，使用 gorm 的软删除
+  - HardDelete 为 true 时直接删除
+
+已经标记删除的行不再重复标记。软删除的行再次出现在上游时，upsert 会覆盖标记列，恢复为未删除。
+只应在全量同步（ts=0）时使用，SyncIncrementalToDB 在增量同步时会忽略该选项。
+设置了 JobName 且从上次中断的页续传时无法对账，SyncResult.ReconcileSkipped 为 true，下一次完整的同步会重新对账。
+*/
+type ReconcileOptions struct {
+	Column     string
+	Value      interface{}
+	HardDelete bool
+	// MaxDeleteRatio 将被删除的行占表中未删除行的比例超过该值时放弃对账并返回错误，例如 0.1，0 表示不检查
+	MaxDeleteRatio float64
+	// MaxDeleteRows 将被删除的行数超过该值时放弃对账并返回错误，0 表示不检查
+	MaxDeleteRows int64
+}
+
+// reconcileBatchSize 每次按主键删除或标记的行数
+const reconcileBatchSize = 500
+
+// keyCollector 记录同步过程中出现过的主键
+type keyCollector struct {
+	fields []*schema.Field
+	seen   map[string]struct{}
+}
+
+func newKeyCollector(db *gorm.DB, dataModel interface{}) (*keyCollector, error) {
+	s, err := parseModel(db, dataModel)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.PrimaryFields) == 0 {
+		return nil, errors.New("reconcile fail: model has no primary key")
+	}
+	return &keyCollector{fields: s.PrimaryFields, seen: make(map[string]struct{})}, nil
+}
+
+// add 记录一页数据的主键，data 为指向结构体切片的指针
+func (k *keyCollector) add(data interface{}) {
+	for _, values := range primaryKeys(k.fields, data) {
+		k.seen[keyString(values)] = struct{}{}
+	}
+}
+
+func parseModel(db *gorm.DB, dataModel interface{}) (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(dataModel); err != nil {
+		return nil, fmt.Errorf("parse model fail: %v", err)
+	}
+	return stmt.Schema, nil
+}
+
+// hasDeletedAt 模型中是否有 gorm.DeletedAt 字段
+func hasDeletedAt(s *schema.Schema) bool {
+	for _, f := range s.Fields {
+		if f.FieldType == reflect.TypeOf(gorm.DeletedAt{}) {
+			return true
+		}
+	}
+	return false
+}
+
+// primaryKeys 返回每一行的主键，data 为指向结构体切片的指针
+func primaryKeys(fields []*schema.Field, data interface{}) [][]interface{} {
+	v := reflect.Indirect(reflect.ValueOf(data))
+	keys := make([][]interface{}, v.Len())
+	for i := range keys {
+		keys[i] = make([]interface{}, len(fields))
+		for j, f := range fields {
+			keys[i][j], _ = f.ValueOf(context.Background(), v.Index(i))
+		}
+	}
+	return keys
+}
+
+func columnNames(fields []*schema.Field) []string {
+	columns := make([]string, len(fields))
+	for i, f := range fields {
+		columns[i] = f.DBName
+	}
+	return columns
+}
+
+// keyString 将主键转换为字符串，数据库返回的 []byte 与结构体中的 string 视为相同
+func keyString(values []interface{}) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		if b, ok := value.([]byte); ok {
+			value = string(b)
+		}
+		parts[i] = fmt.Sprint(value)
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// reconcile 标记或删除表中没有出现在本次同步中的行，返回处理的行数
+func (k *keyCollector) reconcile(db *gorm.DB, dataModel interface{}, opts ReconcileOptions) (int64, error) {
+	// db 可能通过 Table 指定了数据表，使用可以复用的会话，避免条件互相影响
+	db = db.Session(&gorm.Session{})
+	elemType := reflect.Indirect(reflect.ValueOf(dataModel)).Type().Elem()
+	s, err := parseModel(db, dataModel)
+	if err != nil {
+		return 0, err
+	}
+	if opts.Column == "" && !opts.HardDelete && !hasDeletedAt(s) {
+		return 0, errors.New("reconcile fail: need a column, a gorm.DeletedAt field or HardDelete")
+	}
+	value := opts.Value
+	if value == nil {
+		value = time.Now()
+	}
+
+	// 读取表中未删除的主键
+	columns := columnNames(k.fields)
+	query := db.Model(reflect.New(elemType).Interface()).Select(columns)
+	switch {
+	case opts.HardDelete:
+		query = query.Unscoped()
+	case opts.Column != "" && opts.Value == nil:
+		query = query.Where(clause.Eq{Column: clause.Column{Name: opts.Column}, Value: nil})
+	case opts.Column != "":
+		query = query.Where(clause.Or(
+			clause.Eq{Column: clause.Column{Name: opts.Column}, Value: nil},
+			clause.Neq{Column: clause.Column{Name: opts.Column}, Value: value},
+		))
+	}
+	rows, err := query.Rows()
+	if err != nil {
+		return 0, fmt.Errorf("query primary keys fail: %v", err)
+	}
+	var missing [][]interface{}
+	var total int64
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scan primary keys fail: %v", err)
+		}
+		total++
+		if _, ok := k.seen[keyString(values)]; !ok {
+			missing = append(missing, values)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("query primary keys fail: %v", err)
+	}
+
+	count := int64(len(missing))
+	if count == 0 {
+		return 0, nil
+	}
+	if opts.MaxDeleteRows > 0 && count > opts.MaxDeleteRows {
+		return 0, fmt.Errorf("reconcile abort: %d rows would be deleted, more than %d", count, opts.MaxDeleteRows)
+	}
+	if opts.MaxDeleteRatio > 0 && float64(count)/float64(total) > opts.MaxDeleteRatio {
+		return 0, fmt.Errorf("reconcile abort: %d of %d rows would be deleted, more than %.0f%%", count, total, opts.MaxDeleteRatio*100)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if opts.Column != "" && !opts.HardDelete {
+			return forEachKeyBatch(columns, missing, func(cond clause.Expression) error {
+				return tx.Model(reflect.New(elemType).Interface()).Where(cond).Update(opts.Column, value).Error
+			})
+		}
+		_, err := deleteByKeys(tx, elemType, columns, missing, opts.HardDelete)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("reconcile fail: %v", err)
+	}
+	return count, nil
+}
+
+// forEachKeyBatch 按 reconcileBatchSize 分批生成主键条件
+func forEachKeyBatch(columns []string, keys [][]interface{}, fn func(cond clause.Expression) error) error {
+	for i := 0; i < len(keys); i += reconcileBatchSize {
+		end := i + reconcileBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		if err := fn(keysCondition(columns, keys[i:end])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteByKeys 按主键删除，hard 为 false 时模型有 gorm.DeletedAt 字段则为软删除
+func deleteByKeys(db *gorm.DB, elemType reflect.Type, columns []string, keys [][]interface{}, hard bool) (int64, error) {
+	var affected int64
+	err := forEachKeyBatch(columns, keys, func(cond clause.Expression) error {
+		tx := db.Where(cond)
+		if hard {
+			tx = tx.Unscoped()
+		}
+		res := tx.Delete(reflect.New(elemType).Interface())
+		affected += res.RowsAffected
+		return res.Error
+	})
+	return affected, err
+}
+
+// keysCondition 单列主键使用 IN，多列主键使用 OR 连接的条件
+func keysCondition(columns []string, keys [][]interface{}) clause.Expression {
+	if len(columns) == 1 {
+		values := make([]interface{}, len(keys))
+		for i, key := range keys {
+			values[i] = key[0]
+		}
+		return clause.IN{Column: clause.Column{Name: columns[0]}, Values: values}
+	}
+	exprs := make([]clause.Expression, len(keys))
+	for i, key := range keys {
+		eqs := make([]clause.Expression, len(columns))
+		for j, column := range columns {
+			eqs[j] = clause.Eq{Column: clause.Column{Name: column}, Value: key[j]}
+		}
+		exprs[i] = clause.And(eqs...)
+	}
+	return clause.Or(exprs...)
+}
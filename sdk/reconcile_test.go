@@ -0,0 +1,126 @@
+package sdk
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk/sdktest"
+	"gorm.io/gorm"
+)
+
+type testSoftRow struct {
+	Id        int    `json:"id" gorm:"primarykey;autoIncrement:false"`
+	Name      string `json:"name"`
+	DeletedAt gorm.DeletedAt
+}
+
+func Test_ReconcileColumn(t *testing.T) {
+	db := newTestDB(t)
+	api := APIConfig{APIPath: testAPIPath, Reconcile: &ReconcileOptions{Column: "deleted_mark", Value: 1}}
+	m := NewExpvarMetrics("")
+	api.Metrics = m
+	// 已经标记的行不会重复标记
+	for i, n := range []int{5, 3, 3} {
+		if _, err := newTestClient(newTestRows(n, "2023-01-02 00:00:00")).SyncToDB(db, api, &[]testFakeRow{}); err != nil {
+			t.Fatal(err)
+		}
+		if got := m.Counter("ecnu_sync_deleted_total", "api", testAPIPath, "target", "db"); i > 0 && got != 2 {
+			t.Errorf("expected 2 deleted rows after run %d, got %v", i, got)
+		}
+	}
+	var deleted int64
+	db.Model(&testFakeRow{}).Where("deleted_mark = ?", 1).Count(&deleted)
+	if deleted != 2 {
+		t.Errorf("expected 2 deleted rows, got %d", deleted)
+	}
+
+	// 重新出现的行恢复为未删除
+	if _, err := newTestClient(newTestRows(5, "2023-01-02 00:00:00")).SyncToDB(db, api, &[]testFakeRow{}); err != nil {
+		t.Fatal(err)
+	}
+	db.Model(&testFakeRow{}).Where("deleted_mark = ?", 1).Count(&deleted)
+	if deleted != 0 {
+		t.Errorf("expected no deleted rows, got %d", deleted)
+	}
+}
+
+func Test_ReconcileSoftDelete(t *testing.T) {
+	db := newTestDB(t)
+	api := APIConfig{APIPath: testAPIPath, Reconcile: &ReconcileOptions{}}
+	for _, n := range []int{4, 2} {
+		if _, err := newTestClient(newTestRows(n, "2023-01-02 00:00:00")).SyncToDB(db, api, &[]testSoftRow{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var active, total int64
+	db.Model(&testSoftRow{}).Count(&active)
+	db.Unscoped().Model(&testSoftRow{}).Count(&total)
+	if active != 2 || total != 4 {
+		t.Errorf("expected 2 active rows of 4, got %d %d", active, total)
+	}
+
+	// 没有 DeletedAt 字段时需要指定列或者硬删除
+	if _, err := newTestClient(newTestRows(1, "2023-01-02 00:00:00")).SyncToDB(db, api, &[]testFakeRow{}); err == nil || !strings.Contains(err.Error(), "gorm.DeletedAt") {
+		t.Errorf("expected reconcile option error, got %v", err)
+	}
+}
+
+func Test_ReconcileThreshold(t *testing.T) {
+	db := newTestDB(t)
+	api := APIConfig{APIPath: testAPIPath, Reconcile: &ReconcileOptions{HardDelete: true, MaxDeleteRatio: 0.3}}
+	if _, err := newTestClient(newTestRows(10, "2023-01-02 00:00:00")).SyncToDB(db, api, &[]testFakeRow{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newTestClient(newTestRows(5, "2023-01-02 00:00:00")).SyncToDB(db, api, &[]testFakeRow{}); err == nil || !strings.Contains(err.Error(), "reconcile abort: 5 of 10") {
+		t.Errorf("expected reconcile abort, got %v", err)
+	}
+	var total int64
+	db.Model(&testFakeRow{}).Count(&total)
+	if total != 10 {
+		t.Errorf("expected 10 rows, got %d", total)
+	}
+
+	api.Reconcile = &ReconcileOptions{HardDelete: true, MaxDeleteRows: 5}
+	if _, err := newTestClient(newTestRows(5, "2023-01-02 00:00:00")).SyncToDB(db, api, &[]testFakeRow{}); err != nil {
+		t.Fatal(err)
+	}
+	db.Model(&testFakeRow{}).Count(&total)
+	if total != 5 {
+		t.Errorf("expected 5 rows, got %d", total)
+	}
+}
+
+func Test_ReconcileResumed(t *testing.T) {
+	srv := newTestServer(t)
+	if _, err := srv.AddRows(testAPIPath, newTestRows(25, "2023-01-02 00:00:00")); err != nil {
+		t.Fatal(err)
+	}
+	db := newTestDB(t)
+	if _, err := newTestClient(newTestRows(30, "2023-01-02 00:00:00")).SyncToDB(db, APIConfig{APIPath: testAPIPath}, &[]testFakeRow{}); err != nil {
+		t.Fatal(err)
+	}
+	api := APIConfig{APIPath: testAPIPath, PageSize: 10, JobName: "fake", Reconcile: &ReconcileOptions{HardDelete: true}}
+	countRows := func() int64 {
+		var total int64
+		db.Model(&testFakeRow{}).Count(&total)
+		return total
+	}
+
+	srv.InjectFault(sdktest.Fault{Path: testAPIPath, Page: 3, Times: 1, Status: http.StatusInternalServerError, ErrorCode: "X500ER"})
+	if _, err := SyncToDB(db, api, &[]testFakeRow{}); err == nil {
+		t.Fatal("expected sync error")
+	}
+	// 续传时没有对账
+	res, err := SyncToDB(db, api, &[]testFakeRow{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.ReconcileSkipped || res.Deleted != 0 || countRows() != 30 {
+		t.Errorf("resumed sync should skip reconcile: %+v %d", res, countRows())
+	}
+	// 下一次完整的同步正常对账
+	if res, err = SyncToDB(db, api, &[]testFakeRow{}); err != nil || res.ReconcileSkipped || res.Deleted != 5 || countRows() != 25 {
+		t.Errorf("unexpected reconcile result: %+v %v %d", res, err, countRows())
+	}
+}
@@ -0,0 +1,137 @@
+package sdk
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SyncFullRefreshToDB 使用全局 client 全量刷新数据库中的表
+func SyncFullRefreshToDB(db *gorm.DB, api APIConfig, dataModel interface{}) (SyncResult, error) {
+	return syncFullRefreshToDB(GetOpenAPIClient(), db, api, dataModel)
+}
+
+/*
+syncFullRefreshToDB 先将全部数据写入临时表，校验行数后在一个事务中替换正式表
+
+  - 同步过程中读取正式表看到的始终是上一次完整的数据
+  - 上游已经删除的数据会随旧表一起删除
+  - 行数与接口返回的 TotalNum 不一致时，删除临时表并返回错误，正式表保持不变，设置了 DeleteMarker 时带有删除标记的行不写入，也不计入比较
+  - 临时表总是根据模型创建；Migrate 为 MigrateOff 或 MigrateVerify 时正式表需要已经存在，不会通过替换创建正式表
+
+临时表名为 表名_staging_后缀，替换时旧表先改名为 表名_old_后缀 再删除，后缀为 36 进制的纳秒时间戳。
+表名较长时需要注意数据库对表名长度的限制，例如 MySQL 为 64、PostgreSQL 为 63。
+支持 sqlite、mysql、postgres、sqlserver，其中 MySQL 的 DDL 不支持事务，使用一条 RENAME TABLE 同时替换两张表。
+*/
+func syncFullRefreshToDB(r Requester, db *gorm.DB, api APIConfig, dataModel interface{}) (SyncResult, error) {
+	swap, ok := tableSwappers[db.Dialector.Name()]
+	if !ok {
+		return SyncResult{}, fmt.Errorf("full refresh not support dialect: %s", db.Dialector.Name())
+	}
+	db = api.tableDB(db)
+	table := db.Statement.Table
+	if table == "" {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(dataModel); err != nil {
+			return SyncResult{}, fmt.Errorf("parse model fail: %v", err)
+		}
+		table = stmt.Schema.Table
+	}
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	staging := table + "_staging_" + suffix
+	old := table + "_old_" + suffix
+
+	// 临时表不记录同步状态，失败后重新开始；替换后上游已删除的数据随旧表删除，不需要对账
+	api.JobName = ""
+	api.Reconcile = nil
+	api.Table = ""
+	base := db.Session(&gorm.Session{NewDB: true})
+	switch api.Migrate {
+	case MigrateVerify:
+		if err := verifyTable(db, dataModel); err != nil {
+			return SyncResult{}, err
+		}
+	case MigrateOff:
+		if !base.Migrator().HasTable(table) {
+			return SyncResult{}, fmt.Errorf("full refresh %s fail: table not exists and migrate is %s", table, MigrateOff)
+		}
+	}
+	api.Migrate = MigrateCreate
+	res, err := syncToDB(r, base.Table(staging), api, dataModel)
+	if err == nil {
+		// 带有删除标记的行不写入临时表，同步成功时其余的行都已写入
+		marked := res.Rows - res.Written
+		var count int64
+		if err = base.Table(staging).Count(&count).Error; err == nil && count+marked != int64(res.TotalNum) {
+			err = fmt.Errorf("full refresh %s fail: staging rows %d and marked rows %d not equal to totalNum %d", table, count, marked, res.TotalNum)
+		}
+	}
+	if err != nil {
+		if derr := base.Migrator().DropTable(staging); derr != nil {
+			return res, fmt.Errorf("%v, drop staging table fail: %v", err, derr)
+		}
+		return res, err
+	}
+
+	if !base.Migrator().HasTable(table) {
+		if err := base.Migrator().RenameTable(staging, table); err != nil {
+			return res, fmt.Errorf("rename staging table fail: %v", err)
+		}
+		return res, nil
+	}
+	if err := swap(base, table, staging, old); err != nil {
+		return res, fmt.Errorf("swap staging table fail: %v", err)
+	}
+	return res, nil
+}
+
+// tableSwapper 用 staging 替换 table，旧表改名为 old 后删除
+type tableSwapper func(db *gorm.DB, table, staging, old string) error
+
+var tableSwappers = map[string]tableSwapper{
+	"sqlite":    swapTransactional,
+	"postgres":  swapTransactional,
+	"sqlserver": swapSQLServer,
+	"mysql":     swapMySQL,
+}
+
+// swapTransactional sqlite 和 PostgreSQL 的 DDL 支持事务
+func swapTransactional(db *gorm.DB, table, staging, old string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("ALTER TABLE ? RENAME TO ?", clause.Table{Name: table}, clause.Table{Name: old}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("ALTER TABLE ? RENAME TO ?", clause.Table{Name: staging}, clause.Table{Name: table}).Error; err != nil {
+			return err
+		}
+		return tx.Exec("DROP TABLE ?", clause.Table{Name: old}).Error
+	})
+}
+
+// swapSQLServer SQL Server 使用 sp_rename 改名，同样可以在事务中执行
+func swapSQLServer(db *gorm.DB, table, staging, old string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("EXEC sp_rename ?, ?", table, old).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("EXEC sp_rename ?, ?", staging, table).Error; err != nil {
+			return err
+		}
+		return tx.Exec("DROP TABLE ?", clause.Table{Name: old}).Error
+	})
+}
+
+// swapMySQL MySQL 的 DDL 会隐式提交，RENAME TABLE 可以在一条语句中原子地替换多张表
+func swapMySQL(db *gorm.DB, table, staging, old string) error {
+	err := db.Exec("RENAME TABLE ? TO ?, ? TO ?",
+		clause.Table{Name: table}, clause.Table{Name: old},
+		clause.Table{Name: staging}, clause.Table{Name: table},
+	).Error
+	if err != nil {
+		return err
+	}
+	return db.Exec("DROP TABLE ?", clause.Table{Name: old}).Error
+}
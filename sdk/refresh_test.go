@@ -0,0 +1,225 @@
+package sdk
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func Test_SyncFullRefreshToDB(t *testing.T) {
+	var mc *MemoryClient
+	db := newTestDB(t)
+	api := APIConfig{APIPath: testAPIPath, PageSize: 2}
+	// 每次使用新的 MemoryClient，模拟上游数据的变化
+	addRows := func(n int) {
+		mc = newTestClient(newTestRows(n, "2023-01-02 00:00:00"))
+	}
+	countRows := func(table string) int64 {
+		var total int64
+		if err := db.Table(table).Count(&total).Error; err != nil {
+			t.Fatal(err)
+		}
+		return total
+	}
+	// 替换后不应留下临时表和旧表
+	checkTables := func() {
+		t.Helper()
+		tables, err := db.Migrator().GetTables()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, table := range tables {
+			if strings.Contains(table, "_staging_") || strings.Contains(table, "_old_") {
+				t.Errorf("unexpected table %s", table)
+			}
+		}
+	}
+
+	addRows(5)
+	res, err := mc.SyncFullRefreshToDB(db, api, &[]testFakeRow{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Rows != 5 || countRows("test_fake_rows") != 5 {
+		t.Errorf("expected 5 rows, got %d", res.Rows)
+	}
+	checkTables()
+
+	// 上游删除的数据在替换后一起删除
+	addRows(3)
+	if _, err := mc.SyncFullRefreshToDB(db, api, &[]testFakeRow{}); err != nil {
+		t.Fatal(err)
+	}
+	if n := countRows("test_fake_rows"); n != 3 {
+		t.Errorf("expected 3 rows, got %d", n)
+	}
+	checkTables()
+
+	// 翻页过程中数据重复，行数与 TotalNum 不一致，正式表保持不变
+	row := newTestRows(1, "2023-01-02 00:00:00")[0]
+	mc = NewMemoryClient()
+	mc.AddPages(testAPIPath, []interface{}{row}, []interface{}{row})
+	if _, err := mc.SyncFullRefreshToDB(db, api, &[]testFakeRow{}); err == nil || !strings.Contains(err.Error(), "not equal to totalNum 2") {
+		t.Errorf("expected row count error, got %v", err)
+	}
+	if n := countRows("test_fake_rows"); n != 3 {
+		t.Errorf("expected 3 rows, got %d", n)
+	}
+	checkTables()
+
+	// 通过 Table 指定表名
+	addRows(4)
+	if _, err := mc.SyncFullRefreshToDB(db.Table("fake_rows"), api, &[]testFakeRow{}); err != nil {
+		t.Fatal(err)
+	}
+	if n := countRows("fake_rows"); n != 4 {
+		t.Errorf("expected 4 rows, got %d", n)
+	}
+
+	// 带有删除标记的行不写入，也不计入行数的比较
+	marked := api
+	marked.DeleteMarker = &DeleteMarker{Field: "deleted_mark", HardDelete: true}
+	if _, err := newMarkedClient(5, 2, 3).SyncFullRefreshToDB(db, marked, &[]testFakeRow{}); err != nil {
+		t.Fatal(err)
+	}
+	if n := countRows("test_fake_rows"); n != 3 {
+		t.Errorf("expected 3 rows, got %d", n)
+	}
+	checkTables()
+
+	// MigrateOff 时不会通过替换创建正式表
+	off := api
+	off.Migrate = MigrateOff
+	if _, err := mc.SyncFullRefreshToDB(db.Table("missing_rows"), off, &[]testFakeRow{}); err == nil || !strings.Contains(err.Error(), "table not exists") {
+		t.Errorf("expected table not exists error, got %v", err)
+	}
+	if db.Migrator().HasTable("missing_rows") {
+		t.Error("table should not be created when migrate is off")
+	}
+	checkTables()
+}
+
+// recordingConn 记录执行的 SQL，用于检查没有测试环境的数据库生成的语句
+type recordingConn struct {
+	stmts *[]string
+	// failOn 执行包含该字符串的语句时返回错误
+	failOn string
+}
+
+func (c recordingConn) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c recordingConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	for _, arg := range args {
+		query += fmt.Sprintf(" [%v]", arg)
+	}
+	*c.stmts = append(*c.stmts, query)
+	if c.failOn != "" && strings.Contains(query, c.failOn) {
+		return nil, errors.New("exec fail")
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (c recordingConn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c recordingConn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+func (c recordingConn) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	*c.stmts = append(*c.stmts, "BEGIN")
+	return &recordingTx{c}, nil
+}
+
+// recordingTx 事务中的连接，连接本身实现 Commit 时 gorm 会认为已经在事务中而使用 SAVEPOINT
+type recordingTx struct {
+	recordingConn
+}
+
+func (tx *recordingTx) Commit() error {
+	*tx.stmts = append(*tx.stmts, "COMMIT")
+	return nil
+}
+
+func (tx *recordingTx) Rollback() error {
+	*tx.stmts = append(*tx.stmts, "ROLLBACK")
+	return nil
+}
+
+// Test_TableSwapDialects 其他数据库只检查替换表时执行的语句
+func Test_TableSwapDialects(t *testing.T) {
+	cases := []struct {
+		name      string
+		dialector func(conn gorm.ConnPool) gorm.Dialector
+		want      []string
+	}{
+		{"mysql", func(conn gorm.ConnPool) gorm.Dialector {
+			return mysql.New(mysql.Config{Conn: conn, SkipInitializeWithVersion: true})
+		}, []string{
+			"RENAME TABLE `rows` TO `rows_old`, `rows_staging` TO `rows`",
+			"DROP TABLE `rows_old`",
+		}},
+		{"postgres", func(conn gorm.ConnPool) gorm.Dialector {
+			return postgres.New(postgres.Config{Conn: conn})
+		}, []string{
+			"BEGIN",
+			`ALTER TABLE "rows" RENAME TO "rows_old"`,
+			`ALTER TABLE "rows_staging" RENAME TO "rows"`,
+			`DROP TABLE "rows_old"`,
+			"COMMIT",
+		}},
+		{"sqlserver", func(conn gorm.ConnPool) gorm.Dialector {
+			return sqlserver.New(sqlserver.Config{Conn: conn})
+		}, []string{
+			"BEGIN",
+			"EXEC sp_rename @p1, @p2 [rows] [rows_old]",
+			"EXEC sp_rename @p1, @p2 [rows_staging] [rows]",
+			`DROP TABLE "rows_old"`,
+			"COMMIT",
+		}},
+	}
+	for _, c := range cases {
+		var stmts []string
+		conn := recordingConn{stmts: &stmts}
+		db, err := gorm.Open(c.dialector(conn), &gorm.Config{SkipDefaultTransaction: true, DisableAutomaticPing: true, Logger: logger.Discard})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if db.Dialector.Name() != c.name {
+			t.Fatalf("unexpected dialect %s", db.Dialector.Name())
+		}
+		if err := tableSwappers[c.name](db, "rows", "rows_staging", "rows_old"); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if strings.Join(stmts, "\n") != strings.Join(c.want, "\n") {
+			t.Errorf("%s: unexpected statements:\n%s\nexpected:\n%s", c.name, strings.Join(stmts, "\n"), strings.Join(c.want, "\n"))
+		}
+
+		// 改名失败时回滚，不会删除旧表
+		stmts = nil
+		conn.failOn = "rows_staging"
+		if db, err = gorm.Open(c.dialector(conn), &gorm.Config{SkipDefaultTransaction: true, DisableAutomaticPing: true, Logger: logger.Discard}); err != nil {
+			t.Fatal(err)
+		}
+		if err := tableSwappers[c.name](db, "rows", "rows_staging", "rows_old"); err == nil {
+			t.Errorf("%s: expected swap error", c.name)
+		}
+		last := stmts[len(stmts)-1]
+		if strings.HasPrefix(last, "DROP") || (c.name != "mysql" && last != "ROLLBACK") {
+			t.Errorf("%s: unexpected statements after failure:\n%s", c.name, strings.Join(stmts, "\n"))
+		}
+	}
+}
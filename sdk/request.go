@@ -0,0 +1,171 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+/*
+Request 链式构造的接口请求
+
+	var orgs []Organization
+	resp, err := c.R().
+		Path("/api/v1/organization/list").
+		Query("pageNum", 1).
+		Query("pageSize", 100).
+		Into(&orgs).
+		Do(ctx)
+
+Path 可以是相对于 BaseUrl 的路径，也可以是完整的地址。
+没有调用 Method 时，设置了请求体的请求使用 POST，否则使用 GET。
+*/
+type Request struct {
+	client *OAuth2Client
+	method string
+	path   string
+	query  url.Values
+	header map[string]string
+	body   []byte
+	into   interface{}
+	err    error
+}
+
+/*
+Response 接口响应
+
+请求失败时，如果已经收到网关的响应，Do 会同时返回 Response 和错误，
+可以通过 RequestId 和 Header 中的 X-Ca-Error-Code 排查问题。
+*/
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	// RequestId 优先使用 APIResult 中的 requestId，没有时使用 X-Ca-Request-Id 响应头
+	RequestId string
+	ErrCode   int64
+	ErrMsg    string
+	Data      json.RawMessage
+	// Size 响应体的字节数
+	Size int64
+}
+
+// R 创建一个请求
+func (c *OAuth2Client) R() *Request {
+	return &Request{client: c, query: make(url.Values), header: make(map[string]string)}
+}
+
+// Method 设置请求方法
+func (r *Request) Method(method string) *Request {
+	r.method = strings.ToUpper(method)
+	return r
+}
+
+// Path 设置接口路径，可以带有 query 参数
+func (r *Request) Path(path string) *Request {
+	r.path = path
+	return r
+}
+
+// Query 追加一个 query 参数，value 使用 fmt.Sprint 转换为字符串
+func (r *Request) Query(key string, value interface{}) *Request {
+	r.query.Add(key, fmt.Sprint(value))
+	return r
+}
+
+// Queries 追加多个 query 参数，例如 APIConfig.Params()
+func (r *Request) Queries(values url.Values) *Request {
+	for key, vs := range values {
+		for _, v := range vs {
+			r.query.Add(key, v)
+		}
+	}
+	return r
+}
+
+// Header 设置请求头
+func (r *Request) Header(key, value string) *Request {
+	r.header[key] = value
+	return r
+}
+
+// JSON 设置 json 请求体
+func (r *Request) JSON(body interface{}) *Request {
+	content, err := json.Marshal(body)
+	if err != nil {
+		r.err = fmt.Errorf("marshal request body fail: %v", err)
+		return r
+	}
+	r.body = content
+	r.header["Content-Type"] = "application/json"
+	return r
+}
+
+// Form 设置表单请求体
+func (r *Request) Form(values url.Values) *Request {
+	r.body = []byte(values.Encode())
+	r.header["Content-Type"] = "application/x-www-form-urlencoded"
+	return r
+}
+
+// Into 将 APIResult.Data 解析到 out，out 需要是指针
+func (r *Request) Into(out interface{}) *Request {
+	r.into = out
+	return r
+}
+
+// URL 返回请求的完整地址
+func (r *Request) URL() (string, error) {
+	raw := r.path
+	if !strings.HasPrefix(raw, "http://") && !strings.HasPrefix(raw, "https://") {
+		raw = strings.TrimSuffix(r.client.BaseUrl, "/") + "/" + strings.TrimPrefix(raw, "/")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("parse request url fail: %v", err)
+	}
+	if len(r.query) > 0 {
+		query := u.Query()
+		for key, vs := range r.query {
+			for _, v := range vs {
+				query.Add(key, v)
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.String(), nil
+}
+
+// Do 发送请求，ctx 为 nil 时使用 context.Background()
+func (r *Request) Do(ctx context.Context) (*Response, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	u, err := r.URL()
+	if err != nil {
+		return nil, err
+	}
+	method := r.method
+	if method == "" {
+		method = http.MethodGet
+		if r.body != nil {
+			method = http.MethodPost
+		}
+	}
+	resp, err := r.client.do(ctx, u, method, r.header, r.body)
+	if err != nil {
+		return resp, err
+	}
+	if r.into != nil && len(resp.Data) > 0 {
+		// 与 UnmarshalRows 一致，支持 time_format、time_location tag
+		if err := jsonTime.Unmarshal(resp.Data, r.into); err != nil {
+			return resp, fmt.Errorf("parse api data fail: %v", err)
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,123 @@
+package sdk
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk/sdktest"
+)
+
+func Test_RequestURL(t *testing.T) {
+	c := &OAuth2Client{BaseUrl: "https://api.ecnu.edu.cn/"}
+	cases := []struct {
+		req  *Request
+		want string
+	}{
+		{c.R().Path("/api/v1/sync/fakewithts"), "https://api.ecnu.edu.cn/api/v1/sync/fakewithts"},
+		{c.R().Path("api/v1/sync/fakewithts?ts=0").Query("pageNum", 1), "https://api.ecnu.edu.cn/api/v1/sync/fakewithts?pageNum=1&ts=0"},
+		{c.R().Path("/api/v1/search").Query("name", "华东 师范&大学"), "https://api.ecnu.edu.cn/api/v1/search?name=%E5%8D%8E%E4%B8%9C+%E5%B8%88%E8%8C%83%26%E5%A4%A7%E5%AD%A6"},
+		{c.R().Path("https://other.ecnu.edu.cn/api?b=2&a=1"), "https://other.ecnu.edu.cn/api?b=2&a=1"},
+	}
+	for _, cs := range cases {
+		got, err := cs.req.URL()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != cs.want {
+			t.Errorf("expected %s, got %s", cs.want, got)
+		}
+	}
+}
+
+func Test_RequestDo(t *testing.T) {
+	srv := newTestServer(t)
+	if _, err := srv.AddRows(testAPIPath, newTestRows(15, "2023-01-02 00:00:00")); err != nil {
+		t.Fatal(err)
+	}
+	srv.AddData(testOrgPath, map[string]string{"code": "0445", "name": "华东师范大学"})
+	c := GetOpenAPIClient()
+
+	api := APIConfig{APIPath: testAPIPath}
+	api.SetParam("ts", "0")
+	var page DataResult
+	resp, err := c.R().
+		Path(api.APIPath).
+		Queries(api.Params()).
+		Query("pageNum", 2).
+		Query("pageSize", 10).
+		Into(&page).
+		Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.PageNum != 2 || len(page.Rows) != 5 {
+		t.Errorf("unexpected page: %d %d", page.PageNum, len(page.Rows))
+	}
+	if resp.StatusCode != http.StatusOK || resp.RequestId == "" || resp.RequestId != resp.Header.Get("X-Ca-Request-Id") {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	var org struct {
+		Code string `json:"code"`
+		Name string `json:"name"`
+	}
+	resp, err = c.R().Path(testOrgPath).JSON(map[string]string{"code": "0445"}).Into(&org).Do(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if org.Name != "华东师范大学" {
+		t.Errorf("unexpected data: %+v", org)
+	}
+
+	resp, err = c.R().Path("/api/v1/not-found").Do(context.Background())
+	if err == nil {
+		t.Fatal("request should fail")
+	}
+	if resp == nil || resp.StatusCode != http.StatusNotFound || resp.Header.Get("X-Ca-Error-Code") != "A404NF" || resp.RequestId == "" {
+		t.Errorf("failed request should return response: %+v", resp)
+	}
+
+	// token 失效时与 HttpGet 一样自动重试
+	srv.InjectFault(sdktest.InvalidToken(testOrgPath, 1))
+	if _, err := c.R().Path(testOrgPath).Do(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+func Test_Iterator(t *testing.T) {
+	srv := newTestServer(t)
+	if _, err := srv.AddRows(testAPIPath, newTestRows(25, "2023-01-02 00:00:00")); err != nil {
+		t.Fatal(err)
+	}
+	c := GetOpenAPIClient()
+	it := NewIterator(func(ctx context.Context, pageNum int) ([]testFakeRow, error) {
+		var page Page[testFakeRow]
+		_, err := c.R().Path(testAPIPath).Query("pageNum", pageNum).Query("pageSize", 10).Into(&page).Do(ctx)
+		return page.Rows, err
+	})
+	rows, err := it.All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 3 页数据加一次空页
+	if len(rows) != 25 || it.PageNum() != 4 || srv.Requests(testAPIPath) != 4 {
+		t.Errorf("unexpected iterator result: %d rows, page %d", len(rows), it.PageNum())
+	}
+	if rows[24].UpdateTime.IsZero() {
+		t.Error("expected sql_datetime to be parsed")
+	}
+	if it.Next(context.Background()) {
+		t.Error("expected iterator to be done")
+	}
+
+	srv.InjectFault(sdktest.ServerError(testAPIPath, 1, http.StatusInternalServerError))
+	it = NewIterator(func(ctx context.Context, pageNum int) ([]testFakeRow, error) {
+		var page Page[testFakeRow]
+		_, err := c.R().Path(testAPIPath).Query("pageNum", pageNum).Into(&page).Do(ctx)
+		return page.Rows, err
+	})
+	if it.Next(context.Background()) || it.Err() == nil {
+		t.Error("expected iterator error")
+	}
+}
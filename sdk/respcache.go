@@ -0,0 +1,312 @@
+package sdk
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultResponseCacheTTL        = time.Minute
+	DefaultResponseCacheMaxEntries = 1000
+)
+
+/*
+ResponseCacheConfig GET 请求的响应缓存
+
+缓存以 method、url、scope 和凭据（Authorization 或 AppKey 的 hash）为 key，只缓存 200 且 errCode 为 0 的响应，
+不同用户的 token 不会共享缓存，/oauth2/ 下的请求不缓存。
+同步和 Iterator 的分页请求只在 PathTTL 中有匹配的前缀时缓存，不使用默认的 TTL，避免同步读到过期的页。
+过期后如果响应带有 ETag 或 Last-Modified，会发出条件请求，网关返回 304 时继续使用缓存。
+同一时刻相同的 GET 请求只会发出一次，其他请求等待并共享结果。
+请求头中带有 Cache-Control: no-cache 时跳过缓存。
+*/
+type ResponseCacheConfig struct {
+	Enabled bool `json:"enabled"`
+	// TTL 默认缓存时间，默认 1 分钟
+	TTL time.Duration `json:"ttl"`
+	// PathTTL 按路径前缀设置缓存时间，最长前缀优先，小于 0 表示不缓存该路径，分页请求需要在这里设置才会缓存
+	PathTTL map[string]time.Duration `json:"path_ttl"`
+	// MaxEntries 内存 LRU 的最大条目数，默认 1000，设置了 Backend 时无效
+	MaxEntries int `json:"max_entries"`
+	// Backend 自定义缓存后端，例如 redis，默认使用内存 LRU
+	Backend ResponseCache `json:"-"`
+}
+
+// CachedResponse 缓存的响应
+type CachedResponse struct {
+	StatusCode   int         `json:"status_code"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+	ETag         string      `json:"etag"`
+	LastModified string      `json:"last_modified"`
+	Expires      time.Time   `json:"expires"`
+}
+
+// ResponseCache 缓存后端，需要并发安全
+type ResponseCache interface {
+	Get(key string) (CachedResponse, bool)
+	Set(key string, resp CachedResponse)
+	Delete(key string)
+}
+
+// LRUCache 内存 LRU 缓存
+type LRUCache struct {
+	maxEntries int
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key  string
+	resp CachedResponse
+}
+
+func NewLRUCache(maxEntries int) *LRUCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultResponseCacheMaxEntries
+	}
+	return &LRUCache{maxEntries: maxEntries, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (l *LRUCache) Get(key string) (CachedResponse, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.items[key]; ok {
+		l.ll.MoveToFront(e)
+		return e.Value.(*lruEntry).resp, true
+	}
+	return CachedResponse{}, false
+}
+
+func (l *LRUCache) Set(key string, resp CachedResponse) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.items[key]; ok {
+		l.ll.MoveToFront(e)
+		e.Value.(*lruEntry).resp = resp
+		return
+	}
+	l.items[key] = l.ll.PushFront(&lruEntry{key: key, resp: resp})
+	for l.ll.Len() > l.maxEntries {
+		oldest := l.ll.Back()
+		l.ll.Remove(oldest)
+		delete(l.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+func (l *LRUCache) Delete(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.items[key]; ok {
+		l.ll.Remove(e)
+		delete(l.items, key)
+	}
+}
+
+// Len 返回当前缓存的条目数
+func (l *LRUCache) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ll.Len()
+}
+
+type cacheTransport struct {
+	conf  ResponseCacheConfig
+	scope string
+	next  http.RoundTripper
+	cache ResponseCache
+
+	mu       sync.Mutex
+	inflight map[string]*inflightCall
+}
+
+type inflightCall struct {
+	wg   sync.WaitGroup
+	resp CachedResponse
+	err  error
+}
+
+func newCacheTransport(conf ResponseCacheConfig, scopes []string, next http.RoundTripper) http.RoundTripper {
+	if !conf.Enabled {
+		return next
+	}
+	if conf.TTL <= 0 {
+		conf.TTL = DefaultResponseCacheTTL
+	}
+	backend := conf.Backend
+	if backend == nil {
+		backend = NewLRUCache(conf.MaxEntries)
+	}
+	sorted := append([]string(nil), scopes...)
+	sort.Strings(sorted)
+	return &cacheTransport{
+		conf:     conf,
+		scope:    strings.Join(sorted, " "),
+		next:     next,
+		cache:    backend,
+		inflight: make(map[string]*inflightCall),
+	}
+}
+
+// skipCacheKey context 中带有该 key 的请求跳过缓存，不会在请求中添加额外的请求头
+type skipCacheKey struct{}
+
+// withoutCache 返回跳过响应缓存的 context
+func withoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipCacheKey{}, true)
+}
+
+// pageRequestKey context 中带有该 key 的请求为同步或 Iterator 的分页请求
+type pageRequestKey struct{}
+
+// withPageRequest 返回标记为分页请求的 context
+func withPageRequest(ctx context.Context) context.Context {
+	return context.WithValue(ctx, pageRequestKey{}, true)
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ttl := t.ttl(req.URL.Path, req.Context().Value(pageRequestKey{}) != nil)
+	if req.Method != http.MethodGet || ttl <= 0 || req.Header.Get("Range") != "" || strings.HasPrefix(req.URL.Path, "/oauth2/") ||
+		strings.Contains(req.Header.Get("Cache-Control"), "no-cache") || req.Context().Value(skipCacheKey{}) != nil {
+		return t.next.RoundTrip(req)
+	}
+	key := req.Method + " " + req.URL.String() + " " + t.scope + " " + credential(req)
+
+	cached, found := t.cache.Get(key)
+	if found && time.Now().Before(cached.Expires) {
+		return cached.response(req), nil
+	}
+
+	// 相同的请求正在进行中，等待其结果
+	t.mu.Lock()
+	if call, ok := t.inflight[key]; ok {
+		t.mu.Unlock()
+		call.wg.Wait()
+		if call.err != nil {
+			return nil, call.err
+		}
+		return call.resp.response(req), nil
+	}
+	call := new(inflightCall)
+	call.wg.Add(1)
+	t.inflight[key] = call
+	t.mu.Unlock()
+
+	call.resp, call.err = t.fetch(req, key, ttl, cached, found)
+	call.wg.Done()
+
+	t.mu.Lock()
+	delete(t.inflight, key)
+	t.mu.Unlock()
+
+	if call.err != nil {
+		return nil, call.err
+	}
+	return call.resp.response(req), nil
+}
+
+func (t *cacheTransport) fetch(req *http.Request, key string, ttl time.Duration, cached CachedResponse, found bool) (CachedResponse, error) {
+	if found {
+		req = req.Clone(req.Context())
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return CachedResponse{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return CachedResponse{}, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && found {
+		cached.Expires = time.Now().Add(ttl)
+		t.cache.Set(key, cached)
+		return cached, nil
+	}
+
+	result := CachedResponse{
+		StatusCode:   resp.StatusCode,
+		Header:       resp.Header.Clone(),
+		Body:         body,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Expires:      time.Now().Add(ttl),
+	}
+	if resp.StatusCode == http.StatusOK && isSuccessResult(body) {
+		t.cache.Set(key, result)
+	} else if found {
+		t.cache.Delete(key)
+	}
+	return result, nil
+}
+
+// credential 返回请求凭据的 hash，签名认证每次请求的签名不同，使用 AppKey
+func credential(req *http.Request) string {
+	value := req.Header.Get("Authorization")
+	if value == "" {
+		value = req.Header.Get(headerCaKey)
+	}
+	if value == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// ttl 返回路径对应的缓存时间，最长前缀优先，分页请求没有匹配的前缀时不缓存
+func (t *cacheTransport) ttl(path string, paged bool) time.Duration {
+	ttl := t.conf.TTL
+	if paged {
+		ttl = 0
+	}
+	matched := -1
+	for prefix, d := range t.conf.PathTTL {
+		if strings.HasPrefix(path, prefix) && len(prefix) > matched {
+			matched = len(prefix)
+			ttl = d
+		}
+	}
+	return ttl
+}
+
+func (r CachedResponse) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(r.StatusCode) + " " + http.StatusText(r.StatusCode),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+func isSuccessResult(body []byte) bool {
+	var result struct {
+		ErrCode int64 `json:"errCode"`
+	}
+	return json.Unmarshal(body, &result) == nil && result.ErrCode == 0
+}
@@ -0,0 +1,195 @@
+package sdk
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	cc "golang.org/x/oauth2/clientcredentials"
+)
+
+const testOrgPath = "/api/v1/organization/list"
+
+func Test_ResponseCacheTTL(t *testing.T) {
+	srv := newTestServer(t)
+	srv.AddData(testOrgPath, []string{"0445"})
+	err := SetupOAuth2ClientCredentials(OAuth2Config{
+		ClientId:     srv.ClientId,
+		ClientSecret: srv.ClientSecret,
+		BaseUrl:      srv.URL,
+		ResponseCache: ResponseCacheConfig{
+			Enabled: true,
+			TTL:     time.Minute,
+			PathTTL: map[string]time.Duration{"/api/v1/sync": -1},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := srv.AddRows(testAPIPath, newTestRows(3, "2023-01-02 00:00:00")); err != nil {
+		t.Fatal(err)
+	}
+
+	c := GetOpenAPIClient()
+	for i := 0; i < 3; i++ {
+		if _, err := c.HttpGet(srv.URL + testOrgPath); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.GetRows(testAPIPath, 1, 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if srv.Requests(testOrgPath) != 1 {
+		t.Errorf("cached path should be requested once, got %d", srv.Requests(testOrgPath))
+	}
+	if srv.Requests(testAPIPath) != 3 {
+		t.Errorf("path with negative ttl should not be cached, got %d", srv.Requests(testAPIPath))
+	}
+
+	// 下载不经过缓存
+	srv.AddFile(testFilePath, newTestContent(100), "application/pdf")
+	for i := 0; i < 2; i++ {
+		if _, err := c.DownloadTo(context.Background(), testFilePath, io.Discard); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if srv.Requests(testFilePath) != 2 {
+		t.Errorf("download should not be cached, got %d", srv.Requests(testFilePath))
+	}
+}
+
+func Test_ResponseCacheConditionalRequest(t *testing.T) {
+	srv := newTestServer(t)
+	srv.ETag = true
+	srv.AddData(testOrgPath, []string{"0445"})
+	backend := NewLRUCache(10)
+	err := SetupOAuth2ClientCredentials(OAuth2Config{
+		ClientId:      srv.ClientId,
+		ClientSecret:  srv.ClientSecret,
+		BaseUrl:       srv.URL,
+		ResponseCache: ResponseCacheConfig{Enabled: true, TTL: time.Millisecond, Backend: backend},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := GetOpenAPIClient()
+	for i := 0; i < 2; i++ {
+		data, err := c.HttpGet(srv.URL + testOrgPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != `["0445"]` {
+			t.Errorf("unexpected data: %s", data)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	// 过期后发出条件请求，304 时仍然返回缓存的内容
+	if srv.Requests(testOrgPath) != 2 || backend.Len() != 1 {
+		t.Errorf("unexpected requests %d, cache entries %d", srv.Requests(testOrgPath), backend.Len())
+	}
+}
+
+func Test_ResponseCacheSingleflight(t *testing.T) {
+	var hits int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte(`{"errCode":0,"errMsg":"success","data":"ok"}`))
+	}))
+	defer backend.Close()
+
+	client := &http.Client{Transport: newCacheTransport(ResponseCacheConfig{Enabled: true}, nil, http.DefaultTransport)}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(backend.URL + testOrgPath)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+	if hits != 1 {
+		t.Errorf("concurrent identical requests should be collapsed, got %d", hits)
+	}
+}
+
+func Test_LRUCache(t *testing.T) {
+	l := NewLRUCache(2)
+	l.Set("a", CachedResponse{StatusCode: 200})
+	l.Set("b", CachedResponse{StatusCode: 200})
+	l.Get("a")
+	l.Set("c", CachedResponse{StatusCode: 200})
+	if _, ok := l.Get("b"); ok {
+		t.Error("least recently used entry should be evicted")
+	}
+	if _, ok := l.Get("a"); !ok {
+		t.Error("recently used entry should be kept")
+	}
+}
+
+func Test_ResponseCacheCredential(t *testing.T) {
+	srv := newTestServer(t)
+	srv.AddData(testOrgPath, []string{"0445"})
+	conf := OAuth2Config{
+		ClientId:      srv.ClientId,
+		ClientSecret:  srv.ClientSecret,
+		BaseUrl:       srv.URL,
+		ResponseCache: ResponseCacheConfig{Enabled: true, TTL: time.Minute},
+	}
+	if err := SetupOAuth2AuthorizationCode(conf); err != nil {
+		t.Fatal(err)
+	}
+
+	// 两个用户的 token 不共享缓存，userinfo 不缓存
+	tc := cc.Config{ClientID: srv.ClientId, ClientSecret: srv.ClientSecret, TokenURL: srv.URL + "/oauth2/token"}
+	for i := 0; i < 2; i++ {
+		token, err := tc.Token(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := GetClient(token)
+		if _, err := GetUserInfo(client); err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < 2; j++ {
+			resp, err := client.Get(srv.URL + testOrgPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+		}
+	}
+	if srv.Requests("/oauth2/userinfo") != 2 {
+		t.Errorf("userinfo should not be cached, got %d", srv.Requests("/oauth2/userinfo"))
+	}
+	if srv.Requests(testOrgPath) != 2 {
+		t.Errorf("each token should be cached separately, got %d", srv.Requests(testOrgPath))
+	}
+
+	// 同步的分页请求没有在 PathTTL 中设置时不缓存
+	if err := SetupOAuth2ClientCredentials(conf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := srv.AddRows(testAPIPath, newTestRows(3, "2023-01-02 00:00:00")); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := SyncToModel(APIConfig{APIPath: testAPIPath}, &[]testFakeRow{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if srv.Requests(testAPIPath) != 4 {
+		t.Errorf("sync pages should not be cached, got %d", srv.Requests(testAPIPath))
+	}
+}
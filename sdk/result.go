@@ -0,0 +1,176 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+/*
+SyncResult 一次同步的结果，同步失败时也会返回已经完成的部分
+
+  - Written 为写入数据库、文件或模型的行数，写入数据库时包括冲突后没有更新的行
+  - Inserted、Updated、Unchanged 只在同步到数据库并设置了 APIConfig.CountChanges 时统计：
+    每一批写入前按主键（或 UpsertOptions.ConflictColumns）查询已经存在的行，不存在的计入 Inserted，
+    存在的计入 Updated，DoNothing 或 OnlyNewer 时表中已经不旧的行计入 Unchanged，一批中重复的主键只统计一次，
+    不依赖数据库返回的影响行数，各数据库的结果相同
+  - Bytes 为接口响应体的字节数，MemoryClient 等不发出 http 请求的 Requester 为 0
+  - Checkpoint 为同步成功后的检查点：设置了 JobName 时为同步状态中的 LastTS，
+    SyncIncrementalToDB 没有设置 JobName 时为表中最后的更新时间
+*/
+type SyncResult struct {
+	// Pages 请求的页数，包括最后的空页
+	Pages int64
+	// Rows 接口返回的行数，包括带有删除标记的行
+	Rows int64
+	// TotalNum 第一页返回的 totalNum
+	TotalNum int
+	Written  int64
+	// Inserted、Updated、Unchanged 需要设置 APIConfig.CountChanges
+	Inserted  int64
+	Updated   int64
+	Unchanged int64
+	// Deleted 按删除标记或删除对账删除的行数
+	Deleted int64
+	// ReconcileSkipped 设置了 Reconcile，但本次从同步状态中断的页续传，之前的页没有记录主键，没有对账
+	ReconcileSkipped bool
+	// Rejected 因为解析或写入失败没有保存的行数
+	Rejected int64
+	Bytes    int64
+	// FetchDuration、DecodeDuration、WriteDuration 请求接口、解析数据和写入的耗时
+	FetchDuration  time.Duration
+	DecodeDuration time.Duration
+	WriteDuration  time.Duration
+	// FailedRequestIds 失败请求的 RequestId，用于排查问题
+	FailedRequestIds []string
+	// LastPage 本次同步最后写入的页码
+	LastPage   int
+	Checkpoint int64
+}
+
+// upsertCounter 统计每一批写入中新增、更新和没有变化的行数
+type upsertCounter struct {
+	fields []*schema.Field
+	// updatedAt 不为 nil 时只有接口数据更新的行计入 Updated
+	updatedAt *schema.Field
+	doNothing bool
+}
+
+// newUpsertCounter 没有设置 APIConfig.CountChanges 时返回 nil，不统计
+func newUpsertCounter(db *gorm.DB, api APIConfig, dataModel interface{}, conflict clause.OnConflict) (*upsertCounter, error) {
+	if !api.CountChanges {
+		return nil, nil
+	}
+	s, err := parseModel(db, dataModel)
+	if err != nil {
+		return nil, err
+	}
+	u := &upsertCounter{fields: s.PrimaryFields, doNothing: conflict.DoNothing}
+	if len(conflict.Columns) > 0 {
+		u.fields = nil
+		for _, c := range conflict.Columns {
+			u.fields = append(u.fields, s.FieldsByDBName[c.Name])
+		}
+	}
+	if !u.doNothing && api.Upsert != nil && api.Upsert.OnlyNewer {
+		u.updatedAt = s.LookUpField(api.UpdatedAtField)
+	}
+	return u, nil
+}
+
+// count 写入前查询一批数据中已经存在的行并统计，batch 为指向结构体切片的指针
+func (u *upsertCounter) count(db *gorm.DB, batch interface{}, res *SyncResult) error {
+	if u == nil || len(u.fields) == 0 {
+		return nil
+	}
+	v := reflect.Indirect(reflect.ValueOf(batch))
+	keys := primaryKeys(u.fields, batch)
+	if len(keys) == 0 {
+		return nil
+	}
+	columns := columnNames(u.fields)
+	if u.updatedAt != nil {
+		columns = append(columns, u.updatedAt.DBName)
+	}
+	existing := reflect.New(v.Type())
+	err := db.Session(&gorm.Session{}).Unscoped().Select(columns).
+		Where(keysCondition(columnNames(u.fields), keys)).Find(existing.Interface()).Error
+	if err != nil {
+		return fmt.Errorf("count existing rows fail: %v", err)
+	}
+	current := make(map[string]interface{})
+	for i, key := range primaryKeys(u.fields, existing.Interface()) {
+		current[keyString(key)] = nil
+		if u.updatedAt != nil {
+			current[keyString(key)], _ = u.updatedAt.ValueOf(context.Background(), existing.Elem().Index(i))
+		}
+	}
+
+	seen := make(map[string]bool, len(keys))
+	for i, key := range keys {
+		k := keyString(key)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		ts, ok := current[k]
+		switch {
+		case !ok:
+			res.Inserted++
+		case u.doNothing:
+			res.Unchanged++
+		case u.updatedAt != nil:
+			newer, _ := u.updatedAt.ValueOf(context.Background(), v.Index(i))
+			if isNewer(newer, ts) {
+				res.Updated++
+			} else {
+				res.Unchanged++
+			}
+		default:
+			res.Updated++
+		}
+	}
+	return nil
+}
+
+// isNewer 与 OnlyNewer 的条件相同：表中的时间为空或早于接口数据时为 true
+func isNewer(value, current interface{}) bool {
+	a, b := reflect.Indirect(reflect.ValueOf(value)), reflect.Indirect(reflect.ValueOf(current))
+	if !b.IsValid() {
+		return true
+	}
+	if !a.IsValid() {
+		return false
+	}
+	if t, ok := a.Interface().(time.Time); ok {
+		ct, ok := b.Interface().(time.Time)
+		return !ok || ct.IsZero() || t.After(ct)
+	}
+	switch {
+	case a.CanInt() && b.CanInt():
+		return a.Int() > b.Int()
+	case a.CanUint() && b.CanUint():
+		return a.Uint() > b.Uint()
+	case a.CanFloat() && b.CanFloat():
+		return a.Float() > b.Float()
+	}
+	return fmt.Sprint(a.Interface()) > fmt.Sprint(b.Interface())
+}
+
+// countingReader 统计读取的字节数
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
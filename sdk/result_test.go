@@ -0,0 +1,87 @@
+package sdk
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk/sdktest"
+)
+
+func Test_SyncResult(t *testing.T) {
+	srv := newTestServer(t)
+	if _, err := srv.AddRows(testAPIPath, newTestRows(25, "2023-01-02 00:00:00")); err != nil {
+		t.Fatal(err)
+	}
+	db := newTestDB(t)
+	api := APIConfig{APIPath: testAPIPath, PageSize: 10}
+	api.SetParam("ts", "0")
+
+	res, err := SyncToDB(db, api, &[]testFakeRow{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Pages != 4 || res.Rows != 25 || res.TotalNum != 25 || res.Written != 25 || res.Inserted != 0 ||
+		res.LastPage != 3 || res.Bytes == 0 || res.FetchDuration == 0 || res.WriteDuration == 0 || res.Checkpoint != 0 {
+		t.Errorf("unexpected first result: %+v", res)
+	}
+
+	// 设置 CountChanges 时统计新增、更新和没有变化的行数，再次同步时全部为更新，只有更新的数据才写入时全部没有变化
+	api.CountChanges = true
+	if res, err = SyncToDB(db, api, &[]testFakeRow{}); err != nil || res.Written != 25 || res.Inserted != 0 || res.Updated != 25 {
+		t.Errorf("unexpected update result: %+v %v", res, err)
+	}
+	api.Upsert = &UpsertOptions{OnlyNewer: true}
+	if res, err = SyncToDB(db, api, &[]testFakeRow{}); err != nil || res.Updated != 0 || res.Unchanged != 25 {
+		t.Errorf("unexpected unchanged result: %+v %v", res, err)
+	}
+
+	// 设置 JobName 时返回检查点
+	api.JobName = "fake"
+	if res, err = SyncToDB(db, api, &[]testFakeRow{}); err != nil {
+		t.Fatal(err)
+	}
+	state, err := GetSyncState(db, "fake", testAPIPath)
+	if err != nil || res.Checkpoint == 0 || res.Checkpoint != state.LastTS {
+		t.Errorf("unexpected checkpoint: %+v %+v %v", res, state, err)
+	}
+
+	// 失败时返回已经完成的部分和失败请求的 RequestId
+	srv.InjectFault(sdktest.ServerError(testAPIPath, 1, http.StatusInternalServerError))
+	res, err = SyncToFile("jsonl", filepath.Join(t.TempDir(), "rows.jsonl"), api)
+	if err == nil || len(res.FailedRequestIds) != 1 || res.FailedRequestIds[0] == "" || res.Pages != 0 {
+		t.Errorf("unexpected failed result: %+v %v", res, err)
+	}
+	if res, err = SyncToModel(api, &[]testFakeRow{}); err != nil || res.Rows != 25 || res.Written != 25 || res.Bytes == 0 {
+		t.Errorf("unexpected model result: %+v %v", res, err)
+	}
+}
+
+func Test_SyncResultRejected(t *testing.T) {
+	mc := NewMemoryClient()
+	mc.AddPages(testAPIPath,
+		[]interface{}{map[string]interface{}{"id": 1, "name": "a"}},
+		[]interface{}{map[string]interface{}{"id": "bad"}, map[string]interface{}{"id": 3}},
+	)
+	res, err := mc.SyncToDB(newTestDB(t), APIConfig{APIPath: testAPIPath}, &[]testFakeRow{})
+	if err == nil || res.Rows != 3 || res.Written != 1 || res.Rejected != 2 || res.LastPage != 1 {
+		t.Errorf("unexpected rejected result: %+v %v", res, err)
+	}
+}
+
+func Test_SyncResultCountChanges(t *testing.T) {
+	rows := newTestRows(3, "2023-01-02 00:00:00")
+	db := newTestDB(t)
+	api := APIConfig{APIPath: testAPIPath, CountChanges: true, Upsert: &UpsertOptions{OnlyNewer: true}}
+	if _, err := newTestClient(rows[:2]).SyncToDB(db, api, &[]testFakeRow{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 只有 id 为 1 的行更新，id 为 3 的行在同一批中重复，只统计一次
+	rows[0]["updated_at"] = "2023-01-03 00:00:00"
+	rows = append(rows, rows[2])
+	res, err := newTestClient(rows).SyncToDB(db, api, &[]testFakeRow{})
+	if err != nil || res.Written != 4 || res.Inserted != 1 || res.Updated != 1 || res.Unchanged != 1 {
+		t.Errorf("unexpected count result: %+v %v", res, err)
+	}
+}
@@ -0,0 +1,322 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrJobRunning 任务正在运行，同一个任务不会同时运行多次
+var ErrJobRunning = errors.New("job is running")
+
+/*
+Job 定时同步任务
+
+每次运行使用 SyncIncrementalToDB：表为空时全量同步，否则从数据库中最后的更新时间开始增量同步。
+*/
+type Job struct {
+	// Name 任务名称，默认为 APIPath
+	Name string
+	API  APIConfig
+	DB   *gorm.DB
+	// Model 指向切片的指针，例如 &[]FakeRowsWithTS{}
+	Model interface{}
+	// Interval 运行间隔，与 Cron 二选一，使用 Interval 时启动后立即运行一次
+	Interval time.Duration
+	// Cron 5 段 cron 表达式，例如 "*/15 * * * *"，使用本地时区
+	Cron string
+	// Jitter 每次运行前额外随机等待 [0, Jitter)，避免多个任务同时请求接口
+	Jitter time.Duration
+	// Retries 失败后的重试次数
+	Retries int
+	// Backoff 第一次重试前的等待时间，之后每次翻倍，默认 1 秒
+	Backoff time.Duration
+	// MaxBackoff 重试等待时间的上限，默认 1 分钟
+	MaxBackoff time.Duration
+	// Incremental 增量同步的参数名和回退窗口
+	Incremental IncrementalOptions
+}
+
+// JobStatus 任务的运行状态
+type JobStatus struct {
+	Name    string
+	Running bool
+	// LastRun 最近一次开始运行的时间，没有运行过时为零值
+	LastRun time.Time
+	// LastDuration 最近一次运行的耗时，包含重试
+	LastDuration time.Duration
+	// LastResult 最近一次同步的结果，重试时为最后一次尝试的结果
+	LastResult SyncResult
+	// LastError 最近一次运行的错误，成功时为 nil
+	LastError error
+	// NextRun 下一次计划运行的时间
+	NextRun  time.Time
+	Runs     int64
+	Failures int64
+}
+
+/*
+Scheduler 定时运行同步任务
+
+	s := sdk.NewScheduler(nil)
+	err := s.Add(sdk.Job{
+		API:      sdk.APIConfig{APIPath: "/api/v1/sync/fakewithts"},
+		DB:       db,
+		Model:    &[]FakeRowsWithTS{},
+		Interval: 10 * time.Minute,
+		Jitter:   time.Minute,
+		Retries:  3,
+	})
+	go s.Run(ctx)
+	status := s.Status()
+
+ctx 取消后不再开始新的运行，Run 会等待正在进行的同步结束后返回。
+*/
+type Scheduler struct {
+	r    Requester
+	mu   sync.Mutex
+	jobs []*scheduledJob
+	wg   sync.WaitGroup
+	// ctx 为 Run 传入的 ctx，Trigger 使用它中断重试等待
+	ctx context.Context
+	// stopped 在等待运行中的任务之前设置，之后 Trigger 不再启动新的运行
+	stopped bool
+}
+
+type scheduledJob struct {
+	job      Job
+	schedule schedule
+	status   JobStatus
+}
+
+// NewScheduler 创建调度器，r 为 nil 时每次运行使用当时的全局 client，可以在 Setup 之前创建
+func NewScheduler(r Requester) *Scheduler {
+	return &Scheduler{r: r}
+}
+
+// requester 返回运行时使用的 Requester
+func (s *Scheduler) requester() (Requester, error) {
+	if s.r != nil {
+		return s.r, nil
+	}
+	if c := GetOpenAPIClient(); c != nil {
+		return c, nil
+	}
+	return nil, errors.New("client is not initialized")
+}
+
+// Add 添加任务，需要在 Run 之前调用
+func (s *Scheduler) Add(job Job) error {
+	if job.Name == "" {
+		job.Name = job.API.APIPath
+	}
+	if job.API.APIPath == "" || job.DB == nil || job.Model == nil {
+		return fmt.Errorf("add job %s fail: api path, db and model are required", job.Name)
+	}
+	if job.Backoff <= 0 {
+		job.Backoff = time.Second
+	}
+	if job.MaxBackoff <= 0 {
+		job.MaxBackoff = time.Minute
+	}
+	j := &scheduledJob{job: job, status: JobStatus{Name: job.Name}}
+	switch {
+	case job.Interval > 0 && job.Cron != "":
+		return fmt.Errorf("add job %s fail: interval and cron can not be used together", job.Name)
+	case job.Interval > 0:
+		j.schedule = intervalSchedule(job.Interval)
+	case job.Cron != "":
+		cron, err := parseCron(job.Cron)
+		if err != nil {
+			return fmt.Errorf("add job %s fail: %v", job.Name, err)
+		}
+		j.schedule = cron
+	default:
+		return fmt.Errorf("add job %s fail: interval or cron is required", job.Name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.find(job.Name) != nil {
+		return fmt.Errorf("add job %s fail: duplicate job name", job.Name)
+	}
+	s.jobs = append(s.jobs, j)
+	return nil
+}
+
+// Run 运行全部任务，直到 ctx 取消，返回前等待正在进行的同步结束，返回后可以再次调用
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.mu.Lock()
+	if s.ctx != nil {
+		s.mu.Unlock()
+		return errors.New("scheduler is already running")
+	}
+	s.ctx = ctx
+	jobs := append([]*scheduledJob(nil), s.jobs...)
+	s.mu.Unlock()
+
+	for _, j := range jobs {
+		s.wg.Add(1)
+		go func(j *scheduledJob) {
+			defer s.wg.Done()
+			s.loop(ctx, j)
+		}(j)
+	}
+	<-ctx.Done()
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+	s.wg.Wait()
+
+	s.mu.Lock()
+	s.ctx = nil
+	s.stopped = false
+	s.mu.Unlock()
+	return nil
+}
+
+// Trigger 立即运行一次任务，不影响计划时间，任务正在运行时返回 ErrJobRunning
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j := s.find(name)
+	if j == nil {
+		return fmt.Errorf("job %s not found", name)
+	}
+	if s.ctx == nil || s.stopped {
+		return errors.New("scheduler is not running")
+	}
+	if j.status.Running {
+		return ErrJobRunning
+	}
+	j.status.Running = true
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(s.ctx, j)
+	}()
+	return nil
+}
+
+// Status 返回全部任务的状态，按名称排序
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make([]JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		res = append(res, j.status)
+	}
+	sort.Slice(res, func(i, k int) bool { return res[i].Name < res[k].Name })
+	return res
+}
+
+// JobStatus 返回一个任务的状态
+func (s *Scheduler) JobStatus(name string) (JobStatus, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if j := s.find(name); j != nil {
+		return j.status, true
+	}
+	return JobStatus{}, false
+}
+
+func (s *Scheduler) find(name string) *scheduledJob {
+	for _, j := range s.jobs {
+		if j.job.Name == name {
+			return j
+		}
+	}
+	return nil
+}
+
+// loop 按计划运行一个任务，上一次运行未结束时（例如 Trigger 触发）跳过本次
+func (s *Scheduler) loop(ctx context.Context, j *scheduledJob) {
+	now := time.Now()
+	next := now
+	if _, ok := j.schedule.(intervalSchedule); !ok {
+		next = j.schedule.next(now)
+	}
+	for {
+		if next.IsZero() {
+			return
+		}
+		next = next.Add(jitter(j.job.Jitter))
+		s.mu.Lock()
+		j.status.NextRun = next
+		s.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.mu.Lock()
+		running := j.status.Running
+		j.status.Running = true
+		s.mu.Unlock()
+		if !running {
+			s.run(ctx, j)
+		}
+		next = j.schedule.next(time.Now())
+	}
+}
+
+// run 运行一次任务，失败时按指数退避重试，调用前需要将 Running 置为 true
+func (s *Scheduler) run(ctx context.Context, j *scheduledJob) {
+	start := time.Now()
+	s.mu.Lock()
+	j.status.LastRun = start
+	s.mu.Unlock()
+
+	var res SyncResult
+	var err error
+	backoff := j.job.Backoff
+	for attempt := 0; ; attempt++ {
+		var r Requester
+		if r, err = s.requester(); err == nil {
+			res, err = syncIncrementalToDB(r, j.job.DB, j.job.API, j.job.Model, j.job.Incremental)
+		}
+		if err == nil || attempt >= j.job.Retries {
+			break
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		if backoff *= 2; backoff > j.job.MaxBackoff {
+			backoff = j.job.MaxBackoff
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j.status.Running = false
+	j.status.LastDuration = time.Since(start)
+	j.status.LastResult = res
+	j.status.LastError = err
+	j.status.Runs++
+	if err != nil {
+		j.status.Failures++
+	}
+}
+
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
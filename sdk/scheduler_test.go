@@ -0,0 +1,174 @@
+package sdk
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk/sdktest"
+)
+
+func Test_ParseCron(t *testing.T) {
+	loc := time.UTC
+	from := time.Date(2023, 1, 2, 8, 7, 30, 0, loc) // 周一
+	for _, tt := range []struct {
+		expr string
+		next time.Time
+	}{
+		{"*/15 * * * *", time.Date(2023, 1, 2, 8, 15, 0, 0, loc)},
+		{"0 2 * * *", time.Date(2023, 1, 3, 2, 0, 0, 0, loc)},
+		{"30 8 * * 1-5", time.Date(2023, 1, 2, 8, 30, 0, 0, loc)},
+		{"0 0 * * 7", time.Date(2023, 1, 8, 0, 0, 0, 0, loc)},
+		{"0 0 15 * 6", time.Date(2023, 1, 7, 0, 0, 0, 0, loc)},
+		// */2 与 * 相同，只在单数日的周一运行
+		{"0 0 */2 * 1", time.Date(2023, 1, 9, 0, 0, 0, 0, loc)},
+		{"5,10 9-10/1 1 2 *", time.Date(2023, 2, 1, 9, 5, 0, 0, loc)},
+		{"@monthly", time.Date(2023, 2, 1, 0, 0, 0, 0, loc)},
+	} {
+		s, err := parseCron(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next := s.next(from); !next.Equal(tt.next) {
+			t.Errorf("next(%q) = %s, expected %s", tt.expr, next, tt.next)
+		}
+	}
+	for _, expr := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "a * * * *", "5-1 * * * *"} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("expected error for %q", expr)
+		}
+	}
+	s, _ := parseCron("0 0 30 2 *")
+	if next := s.next(from); !next.IsZero() {
+		t.Errorf("expected no next time, got %s", next)
+	}
+}
+
+func newTestScheduler(t *testing.T, job Job) (*Scheduler, *sdktest.Server, context.CancelFunc, chan error) {
+	t.Helper()
+	srv := newTestServer(t)
+	if _, err := srv.AddRows(testAPIPath, newTestRows(25, "2023-01-02 00:00:00")); err != nil {
+		t.Fatal(err)
+	}
+	job.API = APIConfig{APIPath: testAPIPath, PageSize: 10}
+	job.DB = newTestDB(t)
+	job.Model = &[]testFakeRow{}
+	s := NewScheduler(nil)
+	if err := s.Add(job); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return s, srv, cancel, done
+}
+
+// waitStatus 等待任务状态满足条件
+func waitStatus(t *testing.T, s *Scheduler, name string, cond func(JobStatus) bool) JobStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if status, _ := s.JobStatus(name); cond(status) {
+			return status
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	status, _ := s.JobStatus(name)
+	t.Fatalf("wait job status timeout: %+v", status)
+	return status
+}
+
+func Test_Scheduler(t *testing.T) {
+	s, srv, _, _ := newTestScheduler(t, Job{Interval: 20 * time.Millisecond, Jitter: time.Millisecond})
+
+	status := waitStatus(t, s, testAPIPath, func(s JobStatus) bool { return s.Runs >= 2 && !s.Running })
+	if status.LastError != nil || status.Failures != 0 || status.LastRun.IsZero() || !status.NextRun.After(status.LastRun) {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	if srv.Requests(testAPIPath) < 4 {
+		t.Errorf("expected at least 4 requests, got %d", srv.Requests(testAPIPath))
+	}
+	if list := s.Status(); len(list) != 1 || list[0].Name != testAPIPath {
+		t.Errorf("unexpected status list: %+v", list)
+	}
+	if err := s.Add(Job{Name: testAPIPath, API: APIConfig{APIPath: testAPIPath}, DB: newTestDB(t), Model: &[]testFakeRow{}, Cron: "@daily"}); err == nil {
+		t.Error("expected duplicate job error")
+	}
+}
+
+func Test_SchedulerRetry(t *testing.T) {
+	// 在初始化 client 之前创建，运行时使用新的全局 client
+	s := NewScheduler(nil)
+	srv := newTestServer(t)
+	srv.InjectFault(sdktest.ServerError(testAPIPath, 2, http.StatusServiceUnavailable))
+	if _, err := srv.AddRows(testAPIPath, newTestRows(5, "2023-01-02 00:00:00")); err != nil {
+		t.Fatal(err)
+	}
+	job := Job{API: APIConfig{APIPath: testAPIPath}, DB: newTestDB(t), Model: &[]testFakeRow{}, Interval: time.Hour, Retries: 2, Backoff: time.Millisecond}
+	if err := s.Add(job); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+
+	status := waitStatus(t, s, testAPIPath, func(s JobStatus) bool { return s.Runs == 1 })
+	if status.LastError != nil || status.LastResult.Rows != 5 || !status.NextRun.After(time.Now().Add(50*time.Minute)) {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	cancel()
+	<-done
+}
+
+func Test_SchedulerOverlapAndShutdown(t *testing.T) {
+	// 第一次运行时第一页延迟返回
+	s, srv, cancel, done := newTestScheduler(t, Job{Cron: "0 0 1 1 *"})
+	srv.InjectFault(sdktest.Fault{Path: testAPIPath, Page: 1, Times: 1, Delay: 200 * time.Millisecond})
+	waitStatus(t, s, testAPIPath, func(s JobStatus) bool { return !s.NextRun.IsZero() })
+
+	if err := s.Trigger(testAPIPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Trigger(testAPIPath); err != ErrJobRunning {
+		t.Errorf("expected ErrJobRunning, got %v", err)
+	}
+	if err := s.Trigger("notfound"); err == nil {
+		t.Error("expected job not found error")
+	}
+
+	// 取消后等待正在进行的同步结束
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	done <- nil
+	status, _ := s.JobStatus(testAPIPath)
+	if status.Running || status.Runs != 1 || status.LastResult.Rows != 25 || status.LastError != nil {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	if err := s.Trigger(testAPIPath); err == nil {
+		t.Error("expected scheduler is not running error")
+	}
+
+	// 返回后可以再次运行
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { done <- s.Run(ctx) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for err := s.Trigger(testAPIPath); err != nil; err = s.Trigger(testAPIPath) {
+		if time.Now().After(deadline) {
+			t.Fatalf("scheduler should run again, got %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	waitStatus(t, s, testAPIPath, func(s JobStatus) bool { return s.Runs == 2 && !s.Running })
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	done <- nil
+}
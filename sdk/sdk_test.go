@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -32,7 +33,7 @@ func Test_ParseRowsToCSV(t *testing.T) {
 		},
 	}
 
-	filename := "test.csv"
+	filename := filepath.Join(t.TempDir(), "test.csv")
 
 	if err := parseRowsToCSV(rows, filename); err != nil {
 		t.Error(err)
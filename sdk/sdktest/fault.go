@@ -0,0 +1,129 @@
+package sdktest
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Fault 注入到接口请求中的错误
+type Fault struct {
+	// Path 匹配的接口路径，为空时匹配全部接口
+	Path string
+	// Page 匹配的页码，为 0 时匹配全部页
+	Page int
+	// Times 触发次数，为 0 时一直触发
+	Times int
+
+	// Delay 返回前的延迟，用于模拟慢请求
+	Delay time.Duration
+	// Status 返回的 http 状态码，为 0 时只延迟，仍然返回正常数据
+	Status int
+	// ErrorCode、ErrorMessage 写入 X-Ca-Error-Code、X-Ca-Error-Message 响应头
+	ErrorCode    string
+	ErrorMessage string
+	// RetryAfter 写入 Retry-After 响应头，单位秒
+	RetryAfter int
+	// Body 原样返回的响应体，用于模拟格式错误的 json
+	Body string
+	// RevokeToken 为 true 时同时使已发放的 token 失效
+	RevokeToken bool
+	// TruncateAfter 下载接口只返回指定字节后断开连接
+	TruncateAfter int
+
+	triggered int
+}
+
+// InjectFault 注入一个错误，多个错误按注入顺序匹配
+func (s *Server) InjectFault(f Fault) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fault := &f
+	s.faults = append(s.faults, fault)
+	return fault
+}
+
+// ClearFaults 清空已注入的错误
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// InvalidToken 返回 A401OT，并使已发放的 token 失效，SDK 应当重新获取 token
+func InvalidToken(path string, times int) Fault {
+	return Fault{Path: path, Times: times, Status: http.StatusUnauthorized, ErrorCode: "A401OT", ErrorMessage: "Invalid OAuth Token", RevokeToken: true}
+}
+
+// GatewayError 返回网关错误响应头
+func GatewayError(path string, status int, code, message string) Fault {
+	return Fault{Path: path, Status: status, ErrorCode: code, ErrorMessage: message}
+}
+
+// RateLimited 返回 429
+func RateLimited(path string, times, retryAfter int) Fault {
+	return Fault{Path: path, Times: times, Status: http.StatusTooManyRequests, ErrorCode: "A429TL", ErrorMessage: "Too Many Requests", RetryAfter: retryAfter}
+}
+
+// ServerError 返回 5xx
+func ServerError(path string, times, status int) Fault {
+	return Fault{Path: path, Times: times, Status: status, ErrorCode: "X500ER", ErrorMessage: http.StatusText(status)}
+}
+
+// SlowPage 指定页延迟返回
+func SlowPage(path string, page int, delay time.Duration) Fault {
+	return Fault{Path: path, Page: page, Delay: delay}
+}
+
+// MalformedJSON 指定页返回格式错误的 json
+func MalformedJSON(path string, page int) Fault {
+	return Fault{Path: path, Page: page, Status: http.StatusOK, Body: `{"errCode":0,"data":{"rows":[`}
+}
+
+func (s *Server) matchFault(path string, page int) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, f := range s.faults {
+		if f.Path != "" && f.Path != path {
+			continue
+		}
+		if f.Page != 0 && f.Page != page {
+			continue
+		}
+		if f.Times > 0 && f.triggered >= f.Times {
+			continue
+		}
+		f.triggered++
+		if f.RevokeToken {
+			s.tokens = make(map[string]time.Time)
+		}
+		fault := *f
+		return &fault
+	}
+	return nil
+}
+
+// apply 写入错误响应，返回 false 表示只有延迟，需要继续处理正常请求
+func (f *Fault) apply(w http.ResponseWriter, r *http.Request) bool {
+	if f.Delay > 0 {
+		select {
+		case <-time.After(f.Delay):
+		case <-r.Context().Done():
+			return true
+		}
+	}
+	if f.Status == 0 {
+		return false
+	}
+	if f.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(f.RetryAfter))
+	}
+	if f.Body != "" {
+		w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+		w.WriteHeader(f.Status)
+		_, _ = w.Write([]byte(f.Body))
+		return true
+	}
+	writeGatewayError(w, f.Status, f.ErrorCode, f.ErrorMessage)
+	return true
+}
@@ -0,0 +1,135 @@
+package sdktest
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// File AddFile 注册的二进制内容
+type File struct {
+	Content     []byte
+	ContentType string
+	// ContentMD5 为 true 时返回 Content-MD5 响应头
+	ContentMD5 bool
+	// DisableRange 为 true 时忽略 Range 请求，总是返回完整内容
+	DisableRange bool
+}
+
+// Upload 上传接口收到的 multipart/form-data 请求
+type Upload struct {
+	Fields map[string]string
+	Files  []UploadedFile
+}
+
+// UploadedFile 上传的文件
+type UploadedFile struct {
+	FieldName   string
+	FileName    string
+	ContentType string
+	Content     []byte
+}
+
+// AddFile 注册一个返回二进制内容的下载接口，支持 Range、If-Range 和 ETag
+func (s *Server) AddFile(path string, content []byte, contentType string) *File {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f := &File{Content: content, ContentType: contentType}
+	s.files[path] = f
+	return f
+}
+
+// AddUpload 注册一个接收 multipart/form-data 的上传接口，返回 APIResult，data 为上传的文件数
+func (s *Server) AddUpload(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.uploads[path] = nil
+}
+
+// Uploads 返回上传接口收到的全部请求
+func (s *Server) Uploads(path string) []Upload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Upload(nil), s.uploads[path]...)
+}
+
+// TruncatedDownload 下载接口只返回 after 字节后断开连接
+func TruncatedDownload(path string, times, after int) Fault {
+	return Fault{Path: path, Times: times, TruncateAfter: after}
+}
+
+func (s *Server) serveFile(w http.ResponseWriter, r *http.Request, f *File, fault *Fault) {
+	s.mu.Lock()
+	content := f.Content
+	s.mu.Unlock()
+	sum := md5.Sum(content)
+	w.Header().Set("Content-Type", f.ContentType)
+	w.Header().Set("ETag", fmt.Sprintf(`"%x"`, sum))
+	w.Header().Set("X-Ca-Request-Id", randomString())
+	if f.ContentMD5 {
+		w.Header().Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+	}
+	if f.DisableRange {
+		r.Header.Del("Range")
+	}
+	if fault != nil && fault.TruncateAfter > 0 {
+		w = &truncateWriter{ResponseWriter: w, remaining: fault.TruncateAfter}
+	}
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+}
+
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		writeGatewayError(w, http.StatusBadRequest, "A400BR", "invalid multipart body")
+		return
+	}
+	upload := Upload{Fields: make(map[string]string)}
+	for key, values := range r.MultipartForm.Value {
+		upload.Fields[key] = values[0]
+	}
+	for field, headers := range r.MultipartForm.File {
+		for _, h := range headers {
+			file, err := h.Open()
+			if err != nil {
+				writeGatewayError(w, http.StatusBadRequest, "A400BR", err.Error())
+				return
+			}
+			content, _ := io.ReadAll(file)
+			file.Close()
+			upload.Files = append(upload.Files, UploadedFile{
+				FieldName:   field,
+				FileName:    h.Filename,
+				ContentType: h.Header.Get("Content-Type"),
+				Content:     content,
+			})
+		}
+	}
+	s.mu.Lock()
+	s.uploads[r.URL.Path] = append(s.uploads[r.URL.Path], upload)
+	s.mu.Unlock()
+	writeResult(w, len(upload.Files))
+}
+
+// truncateWriter 写入指定字节后返回错误，模拟连接中断
+type truncateWriter struct {
+	http.ResponseWriter
+	remaining int
+}
+
+func (w *truncateWriter) Write(p []byte) (int, error) {
+	if len(p) <= w.remaining {
+		w.remaining -= len(p)
+		return w.ResponseWriter.Write(p)
+	}
+	n, _ := w.ResponseWriter.Write(p[:w.remaining])
+	w.remaining = 0
+	// 确保已写入的部分发送给客户端
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+	return n, io.ErrClosedPipe
+}
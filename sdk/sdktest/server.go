@@ -0,0 +1,439 @@
+/*
+Package sdktest 提供一个基于 httptest 的 ECNU OpenAPI 网关模拟服务，用于离线测试。
+
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	srv.AddRows("/api/v1/sync/fakewithts", rows)
+	sdk.InitOAuth2ClientCredentials(sdk.OAuth2Config{
+		ClientId:     srv.ClientId,
+		ClientSecret: srv.ClientSecret,
+		BaseUrl:      srv.URL,
+	})
+
+模拟服务实现了 /oauth2/token、/oauth2/authorize、/oauth2/userinfo，
+以及按 APIResult 格式返回的翻页接口，并可以通过 InjectFault 注入各类错误。
+*/
+package sdktest
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultClientId     = "sdktest-client-id"
+	DefaultClientSecret = "sdktest-client-secret"
+	DefaultTokenExpires = time.Hour
+	DefaultPageSize     = 10
+
+	DefaultUpdatedAtField   = "updated_at"
+	DefaultDeletedMarkField = "deleted_mark"
+)
+
+var timeZoneShanghai = time.FixedZone("Asia/Shanghai", 8*3600)
+
+// UserInfo /oauth2/userinfo 返回的用户信息
+type UserInfo struct {
+	UserId     string `json:"userId"`
+	Name       string `json:"name"`
+	VpnEnabled int    `json:"vpnEnabled"`
+}
+
+// API 一个翻页接口的模拟数据
+type API struct {
+	Path string
+	Rows []map[string]interface{}
+	// UpdatedAtField 用于 ts 增量参数过滤的字段，支持 sql datetime 字符串和 unix 时间戳，默认 updated_at
+	UpdatedAtField string
+	// DeletedMarkField 软删除标记字段，非 0 的行只在 full=1 时返回，默认 deleted_mark
+	DeletedMarkField string
+}
+
+// Server 模拟的 ECNU OpenAPI 网关
+type Server struct {
+	*httptest.Server
+
+	ClientId     string
+	ClientSecret string
+	TokenExpires time.Duration
+	UserInfo     UserInfo
+	// AppKey、AppSecret 签名认证使用的密钥，请求带有 X-Ca-Key 时校验签名而不是 token
+	AppKey    string
+	AppSecret string
+	// ETag 为 true 时，非翻页接口返回 ETag 响应头，并对 If-None-Match 返回 304
+	ETag bool
+
+	mu       sync.Mutex
+	apis     map[string]*API
+	data     map[string]interface{}
+	files    map[string]*File
+	uploads  map[string][]Upload
+	tokens   map[string]time.Time
+	codes    map[string]bool
+	nonces   map[string]bool
+	faults   []*Fault
+	requests map[string]int
+	headers  map[string]http.Header
+}
+
+// NewServer 启动一个模拟网关，使用完毕后需要调用 Close
+func NewServer() *Server {
+	s := &Server{
+		ClientId:     DefaultClientId,
+		ClientSecret: DefaultClientSecret,
+		TokenExpires: DefaultTokenExpires,
+		AppKey:       DefaultAppKey,
+		AppSecret:    DefaultAppSecret,
+		UserInfo:     UserInfo{UserId: "10000000000", Name: "测试用户", VpnEnabled: 1},
+		apis:         make(map[string]*API),
+		data:         make(map[string]interface{}),
+		files:        make(map[string]*File),
+		uploads:      make(map[string][]Upload),
+		tokens:       make(map[string]time.Time),
+		codes:        make(map[string]bool),
+		nonces:       make(map[string]bool),
+		requests:     make(map[string]int),
+		headers:      make(map[string]http.Header),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth2/token", s.handleToken)
+	mux.HandleFunc("/oauth2/authorize", s.handleAuthorize)
+	mux.HandleFunc("/oauth2/userinfo", s.handleUserInfo)
+	mux.HandleFunc("/", s.handleAPI)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// AddRows 注册一个翻页接口，rows 可以是任意可以 json 序列化的切片，例如 []FakeRowsWithTS
+func (s *Server) AddRows(path string, rows interface{}) (*API, error) {
+	content, err := json.Marshal(rows)
+	if err != nil {
+		return nil, err
+	}
+	return s.addRowsJSON(path, content)
+}
+
+// AddFixture 从 json 文件注册一个翻页接口，文件内容为行数据的数组
+func (s *Server) AddFixture(path, fixtureFile string) (*API, error) {
+	content, err := os.ReadFile(fixtureFile)
+	if err != nil {
+		return nil, err
+	}
+	return s.addRowsJSON(path, content)
+}
+
+func (s *Server) addRowsJSON(path string, content []byte) (*API, error) {
+	api := &API{
+		Path:             path,
+		UpdatedAtField:   DefaultUpdatedAtField,
+		DeletedMarkField: DefaultDeletedMarkField,
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(content)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&api.Rows); err != nil {
+		return nil, fmt.Errorf("rows must be an array of objects: %v", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apis[path] = api
+	return api, nil
+}
+
+// AddData 注册一个非翻页接口，data 作为 APIResult.Data 原样返回
+func (s *Server) AddData(path string, data interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[path] = data
+}
+
+// Requests 返回某个路径被请求的次数
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+// LastHeader 返回某个路径最近一次请求的请求头，没有请求时返回 nil
+func (s *Server) LastHeader(path string) http.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.headers[path]
+}
+
+// RevokeTokens 使所有已发放的 token 失效，下一次接口调用会返回 A401OT
+func (s *Server) RevokeTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = make(map[string]time.Time)
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	s.count(r)
+	if r.Method != http.MethodPost {
+		writeTokenError(w, http.StatusMethodNotAllowed, "invalid_request")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeTokenError(w, http.StatusBadRequest, "invalid_request")
+		return
+	}
+	clientId, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientId = r.PostForm.Get("client_id")
+		clientSecret = r.PostForm.Get("client_secret")
+	}
+	if clientId != s.ClientId || clientSecret != s.ClientSecret {
+		writeTokenError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+
+	switch r.PostForm.Get("grant_type") {
+	case "client_credentials":
+	case "authorization_code":
+		s.mu.Lock()
+		valid := s.codes[r.PostForm.Get("code")]
+		delete(s.codes, r.PostForm.Get("code"))
+		s.mu.Unlock()
+		if !valid {
+			writeTokenError(w, http.StatusBadRequest, "invalid_grant")
+			return
+		}
+	default:
+		writeTokenError(w, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+
+	token := randomString()
+	s.mu.Lock()
+	s.tokens[token] = time.Now().Add(s.TokenExpires)
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int64(s.TokenExpires.Seconds()),
+	})
+}
+
+// handleAuthorize 模拟用户已同意授权，直接携带 code 跳转回 redirect_uri
+func (s *Server) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	s.count(r)
+	query := r.URL.Query()
+	if query.Get("client_id") != s.ClientId {
+		http.Error(w, "invalid client_id", http.StatusBadRequest)
+		return
+	}
+	redirect, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || query.Get("redirect_uri") == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	code := randomString()
+	s.mu.Lock()
+	s.codes[code] = true
+	s.mu.Unlock()
+
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", query.Get("state"))
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *Server) handleUserInfo(w http.ResponseWriter, r *http.Request) {
+	s.count(r)
+	if !s.authorize(w, r) {
+		return
+	}
+	writeResult(w, s.UserInfo)
+}
+
+func (s *Server) handleAPI(w http.ResponseWriter, r *http.Request) {
+	s.count(r)
+	query := r.URL.Query()
+	pageNum := intParam(query, "pageNum", 1)
+
+	fault := s.matchFault(r.URL.Path, pageNum)
+	if fault != nil && fault.apply(w, r) {
+		return
+	}
+	if !s.authorize(w, r) {
+		return
+	}
+
+	s.mu.Lock()
+	api, isRows := s.apis[r.URL.Path]
+	data, isData := s.data[r.URL.Path]
+	file, isFile := s.files[r.URL.Path]
+	_, isUpload := s.uploads[r.URL.Path]
+	s.mu.Unlock()
+
+	switch {
+	case isFile:
+		s.serveFile(w, r, file, fault)
+	case isUpload:
+		s.handleUpload(w, r)
+	case isRows:
+		pageSize := intParam(query, "pageSize", DefaultPageSize)
+		rows := api.filter(query)
+		start := (pageNum - 1) * pageSize
+		end := start + pageSize
+		if start > len(rows) || start < 0 {
+			start = len(rows)
+		}
+		if end > len(rows) {
+			end = len(rows)
+		}
+		writeResult(w, map[string]interface{}{
+			"totalNum": len(rows),
+			"pageSize": pageSize,
+			"pageNum":  pageNum,
+			"rows":     rows[start:end],
+		})
+	case isData:
+		if s.ETag {
+			content, _ := json.Marshal(data)
+			etag := fmt.Sprintf(`"%x"`, sha1.Sum(content))
+			w.Header().Set("ETag", etag)
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		writeResult(w, data)
+	default:
+		writeGatewayError(w, http.StatusNotFound, "A404NF", "api not found")
+	}
+}
+
+// authorize 校验 Bearer token，失败时按网关的方式返回 A401OT，带有 X-Ca-Key 时校验签名
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("X-Ca-Key") != "" {
+		return s.verifySignature(w, r)
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	s.mu.Lock()
+	expires, ok := s.tokens[token]
+	s.mu.Unlock()
+	if !ok || time.Now().After(expires) {
+		writeGatewayError(w, http.StatusUnauthorized, "A401OT", "Invalid OAuth Token")
+		return false
+	}
+	return true
+}
+
+func (s *Server) count(r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[r.URL.Path]++
+	s.headers[r.URL.Path] = r.Header.Clone()
+}
+
+/*
+filter 模拟增量参数
+
+	ts=0 或不传：返回全部未删除的数据
+	ts>0：返回 UpdatedAtField 不早于 ts 的未删除数据
+	full=1：同时返回已删除（DeletedMarkField 非 0）的数据
+*/
+func (api *API) filter(query url.Values) []map[string]interface{} {
+	ts := int64(intParam(query, "ts", 0))
+	full := query.Get("full") == "1"
+	rows := make([]map[string]interface{}, 0, len(api.Rows))
+	for _, row := range api.Rows {
+		if !full && isDeleted(row[api.DeletedMarkField]) {
+			continue
+		}
+		if ts > 0 {
+			updatedAt, ok := parseTS(row[api.UpdatedAtField])
+			if ok && updatedAt < ts {
+				continue
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func isDeleted(mark interface{}) bool {
+	switch v := mark.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case json.Number:
+		return v.String() != "0"
+	case string:
+		return v != "" && v != "0"
+	}
+	return true
+}
+
+func parseTS(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		i, err := v.Int64()
+		return i, err == nil
+	case string:
+		t, err := time.ParseInLocation("2006-01-02 15:04:05", v, timeZoneShanghai)
+		if err != nil {
+			t, err = time.Parse(time.RFC3339, v)
+		}
+		return t.Unix(), err == nil
+	}
+	return 0, false
+}
+
+func intParam(query url.Values, key string, defaultValue int) int {
+	v, err := strconv.Atoi(query.Get(key))
+	if err != nil {
+		return defaultValue
+	}
+	return v
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeResult(w http.ResponseWriter, data interface{}) {
+	requestId := randomString()
+	w.Header().Set("X-Ca-Request-Id", requestId)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"errCode":   0,
+		"errMsg":    "success",
+		"requestId": requestId,
+		"data":      data,
+	})
+}
+
+func writeGatewayError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("X-Ca-Request-Id", randomString())
+	w.Header().Set("X-Ca-Error-Code", code)
+	w.Header().Set("X-Ca-Error-Message", message)
+	w.WriteHeader(status)
+}
+
+func writeTokenError(w http.ResponseWriter, status int, code string) {
+	writeJSON(w, status, map[string]string{"error": code})
+}
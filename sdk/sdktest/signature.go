@@ -0,0 +1,120 @@
+package sdktest
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultAppKey    = "sdktest-app-key"
+	DefaultAppSecret = "sdktest-app-secret"
+
+	// signatureExpires 时间戳与服务器时间相差超过 15 分钟的请求视为过期，与网关一致
+	signatureExpires = 15 * time.Minute
+)
+
+// verifySignature 按网关的规则校验签名，失败时按网关的方式返回错误
+func (s *Server) verifySignature(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("X-Ca-Key") != s.AppKey {
+		writeGatewayError(w, http.StatusBadRequest, "A400IK", "Invalid AppKey")
+		return false
+	}
+	ts, err := strconv.ParseInt(r.Header.Get("X-Ca-Timestamp"), 10, 64)
+	if err != nil || time.Since(time.UnixMilli(ts)).Abs() > signatureExpires {
+		writeGatewayError(w, http.StatusBadRequest, "A400IT", "Invalid Timestamp")
+		return false
+	}
+	nonce := r.Header.Get("X-Ca-Nonce")
+	s.mu.Lock()
+	used := s.nonces[nonce]
+	s.nonces[nonce] = true
+	s.mu.Unlock()
+	if nonce == "" || used {
+		writeGatewayError(w, http.StatusBadRequest, "A400IN", "Nonce Used")
+		return false
+	}
+
+	var body []byte
+	if r.Body != nil {
+		body, _ = io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	form := mediaType == "application/x-www-form-urlencoded"
+	if len(body) > 0 && !form {
+		sum := md5.Sum(body)
+		if r.Header.Get("Content-MD5") != base64.StdEncoding.EncodeToString(sum[:]) {
+			writeGatewayError(w, http.StatusBadRequest, "A400IM", "Invalid Content-MD5")
+			return false
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(r.Method + "\n")
+	for _, name := range []string{"Accept", "Content-MD5", "Content-Type", "Date"} {
+		sb.WriteString(r.Header.Get(name) + "\n")
+	}
+	var headers []string
+	for _, name := range strings.Split(r.Header.Get("X-Ca-Signature-Headers"), ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			headers = append(headers, name)
+		}
+	}
+	sort.Strings(headers)
+	for _, name := range headers {
+		sb.WriteString(name + ":" + r.Header.Get(name) + "\n")
+	}
+	params := make(map[string]string)
+	for key, values := range r.URL.Query() {
+		params[key] = values[0]
+	}
+	if form {
+		values, _ := url.ParseQuery(string(body))
+		for key, v := range values {
+			if _, ok := params[key]; !ok {
+				params[key] = v[0]
+			}
+		}
+	}
+	sb.WriteString(r.URL.Path)
+	if len(params) > 0 {
+		keys := make([]string, 0, len(params))
+		for key := range params {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for i, key := range keys {
+			if i == 0 {
+				sb.WriteString("?")
+			} else {
+				sb.WriteString("&")
+			}
+			sb.WriteString(key)
+			if params[key] != "" {
+				sb.WriteString("=" + params[key])
+			}
+		}
+	}
+
+	mac := hmac.New(sha256.New, []byte(s.AppSecret))
+	mac.Write([]byte(sb.String()))
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if r.Header.Get("X-Ca-Signature") != expected {
+		// 网关会在错误信息中返回服务端的 StringToSign，换行替换为 #
+		writeGatewayError(w, http.StatusBadRequest, "A400IS",
+			"Invalid Signature, Server StringToSign:`"+strings.ReplaceAll(sb.String(), "\n", "#")+"`")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,132 @@
+package sdk
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk/sdktest"
+)
+
+type captureTransport struct {
+	req *http.Request
+}
+
+func (t *captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.req = req
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+}
+
+/*
+Test_SignatureVectors 使用公开的样例校验签名
+
+  - 待签名字符串：阿里云 API 网关文档「使用摘要签名认证方式调用 API」中的表单请求示例，
+    文档没有公开示例的 AppSecret，所以示例中的 X-Ca-Signature 无法校验
+  - HmacSHA256：RFC 4231 的 Test Case 2
+  - Content-MD5：RFC 1321 中 "abc" 的 MD5
+*/
+func Test_SignatureVectors(t *testing.T) {
+	capture := &captureTransport{}
+	st := newSignatureTransport(SignatureConfig{AppKey: "203753385", AppSecret: "secret"}, capture)
+	st.now = func() time.Time { return time.UnixMilli(1525872629832) }
+	st.nonce = func() string { return "c9f15cbf-f4ac-4a6c-b54d-f51abf4b5b44" }
+
+	body := "username=xiaoming&password=123456789"
+	req, _ := http.NewRequest(http.MethodPost, "http://api.aliyun.com/http2test/test?param1=test", strings.NewReader(body))
+	req.Header.Set("Accept", "application/json; charset=utf-8")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	req.Header.Set("Date", "Wed, 09 May 2018 13:30:29 GMT+00:00")
+	req.Header.Set("Ca_version", "1")
+	if _, err := st.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Header.Get(headerCaSignature) != "" {
+		t.Error("original request should not be modified")
+	}
+
+	signed := capture.req
+	if got := signed.Header.Get(headerContentMD5); got != "" {
+		t.Errorf("form request should not have Content-MD5, got %q", got)
+	}
+	form, _ := url.ParseQuery(body)
+	stringToSign, headers := StringToSign(signed, strings.Split(signed.Header.Get(headerCaSignatureHeaders), ","), form)
+	want := "POST\n" +
+		"application/json; charset=utf-8\n" +
+		"\n" +
+		"application/x-www-form-urlencoded; charset=utf-8\n" +
+		"Wed, 09 May 2018 13:30:29 GMT+00:00\n" +
+		"x-ca-key:203753385\n" +
+		"x-ca-nonce:c9f15cbf-f4ac-4a6c-b54d-f51abf4b5b44\n" +
+		"x-ca-signature-method:HmacSHA256\n" +
+		"x-ca-timestamp:1525872629832\n" +
+		"/http2test/test?param1=test&password=123456789&username=xiaoming"
+	if stringToSign != want {
+		t.Errorf("unexpected string to sign:\n%q\n%q", stringToSign, want)
+	}
+	if headers != "x-ca-key,x-ca-nonce,x-ca-signature-method,x-ca-timestamp" {
+		t.Errorf("unexpected signature headers: %s", headers)
+	}
+	if got := signed.Header.Get(headerCaSignature); got != Sign("secret", want) {
+		t.Errorf("signature should be computed from the string to sign, got %q", got)
+	}
+
+	// RFC 4231 Test Case 2: 5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843
+	if got := Sign("Jefe", "what do ya want for nothing?"); got != "W9zBRr9gdU5qBCQmCJV1x1oAPwidJzmDnexYuWTsOEM=" {
+		t.Errorf("unexpected HmacSHA256: %s", got)
+	}
+
+	// RFC 1321: MD5("abc") = 900150983cd24fb0d6963f7d28e17f72
+	req, _ = http.NewRequest(http.MethodPost, "http://api.aliyun.com/test", strings.NewReader("abc"))
+	req.Header.Set("Content-Type", "application/json")
+	if _, err := st.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+	if got := capture.req.Header.Get(headerContentMD5); got != "kAFQmDzST7DWlj99KOF/cg==" {
+		t.Errorf("unexpected Content-MD5: %s", got)
+	}
+}
+
+func Test_SignatureClient(t *testing.T) {
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	if _, err := srv.AddRows(testAPIPath, newTestRows(15, "2023-01-02 00:00:00")); err != nil {
+		t.Fatal(err)
+	}
+	srv.AddData(testOrgPath, []string{"0445"})
+
+	cf := OAuth2Config{
+		BaseUrl:   srv.URL,
+		Signature: SignatureConfig{AppKey: srv.AppKey, AppSecret: srv.AppSecret},
+	}
+	if err := cf.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetupOAuth2ClientCredentials(cf); err != nil {
+		t.Fatal(err)
+	}
+	rows := []testFakeRow{}
+	if _, err := SyncToModel(APIConfig{APIPath: testAPIPath, PageSize: 10}, &rows); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 15 {
+		t.Errorf("expected 15 rows, got %d", len(rows))
+	}
+	body := strings.NewReader(`{"code":"0445"}`)
+	if _, err := CallAPI(srv.URL+testOrgPath, http.MethodPost, map[string]string{"Content-Type": "application/json"}, body); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.Requests("/oauth2/token"); n != 0 {
+		t.Errorf("signature mode should not fetch token, got %d", n)
+	}
+
+	cf.Signature.AppSecret = "wrong-secret"
+	if err := SetupOAuth2ClientCredentials(cf); err != nil {
+		t.Fatal(err)
+	}
+	_, err := GetOpenAPIClient().HttpGet(srv.URL + testOrgPath)
+	if err == nil || !strings.Contains(err.Error(), "A400IS") {
+		t.Errorf("wrong secret should fail with A400IS, got %v", err)
+	}
+}
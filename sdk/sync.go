@@ -1,11 +1,14 @@
 package sdk
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"net/url"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -16,7 +19,23 @@ type APIConfig struct {
 	PageSize       int    `json:"page_size"`
 	BatchSize      int    `json:"batch_size"`
 	UpdatedAtField string
-	params         url.Values
+	// JobName 不为空时，SyncToDB 在数据库中记录同步状态，中断后可以续传，详见 SyncState
+	JobName string `json:"job_name"`
+	// Reconcile 不为 nil 时，SyncToDB 成功后标记或删除本次没有同步到的行，详见 ReconcileOptions
+	Reconcile *ReconcileOptions `json:"-"`
+	// DeleteMarker 不为 nil 时，SyncToDB 删除带有上游删除标记的行而不是写入，详见 DeleteMarker
+	DeleteMarker *DeleteMarker `json:"delete_marker"`
+	// Upsert 写入时冲突的处理方式，为 nil 时更新全部列，详见 UpsertOptions
+	Upsert *UpsertOptions `json:"upsert"`
+	// Table 写入的表名，为空时使用模型的表名或 db.Table 指定的表名，同一个模型可以同步到多张表
+	Table string `json:"table"`
+	// Migrate 同步前数据表结构的处理方式，默认 MigrateAuto
+	Migrate string `json:"migrate"`
+	// CountChanges 为 true 时 SyncToDB 每一批写入前多查询一次已经存在的行，统计 SyncResult 的 Inserted、Updated、Unchanged
+	CountChanges bool `json:"count_changes"`
+	// Metrics 本次同步使用的指标，默认使用 client 上配置的 Metrics
+	Metrics Metrics `json:"-"`
+	params  url.Values
 }
 
 func (api *APIConfig) SetDefault() {
@@ -54,18 +73,21 @@ func (api *APIConfig) DelParam(key string) {
 	api.params.Del(key)
 }
 
-func (api *APIConfig) ParamEncode() string {
-	return api.params.Encode()
+// Params 返回参数的副本，可以传给 Request.Queries
+func (api *APIConfig) Params() url.Values {
+	params := make(url.Values, len(api.params))
+	for key, values := range api.params {
+		params[key] = append([]string(nil), values...)
+	}
+	return params
 }
 
-func SyncToCSV(fileName string, api APIConfig) (int64, error) {
-	mode := "csv"
-	return SyncToFile(mode, fileName, api)
+func (api *APIConfig) ParamEncode() string {
+	return api.params.Encode()
 }
 
-func SyncToFile(mode string, fileName string, api APIConfig) (int64, error) {
-	c := GetOpenAPIClient()
-	api.SetDefault()
+// fullPath 返回拼接了参数的接口地址
+func (api *APIConfig) fullPath() string {
 	apiPath := api.APIPath
 	if api.ParamEncode() != "" {
 		if strings.Contains(apiPath, "?") {
@@ -74,74 +96,287 @@ func SyncToFile(mode string, fileName string, api APIConfig) (int64, error) {
 			apiPath = api.APIPath + "?" + api.ParamEncode()
 		}
 	}
-	rows, err := c.GetAllRows(apiPath, api.PageSize)
+	return apiPath
+}
+
+func SyncToCSV(fileName string, api APIConfig) (SyncResult, error) {
+	mode := "csv"
+	return SyncToFile(mode, fileName, api)
+}
+
+// SyncToFile 使用全局 client 同步到文件
+func SyncToFile(mode string, fileName string, api APIConfig) (SyncResult, error) {
+	return syncToFile(GetOpenAPIClient(), mode, fileName, api)
+}
+
+// SyncToModel 使用全局 client 同步到模型
+func SyncToModel(api APIConfig, dataModel interface{}) (SyncResult, error) {
+	return syncToModel(GetOpenAPIClient(), api, dataModel)
+}
+
+// SyncToDB 使用全局 client 同步到数据库
+func SyncToDB(db *gorm.DB, api APIConfig, dataModel interface{}) (SyncResult, error) {
+	return syncToDB(GetOpenAPIClient(), db, api, dataModel)
+}
+
+// SyncIncrementalToDB 使用全局 client 增量同步到数据库
+func SyncIncrementalToDB(db *gorm.DB, api APIConfig, dataModel interface{}, opts IncrementalOptions) (SyncResult, error) {
+	return syncIncrementalToDB(GetOpenAPIClient(), db, api, dataModel, opts)
+}
+
+// IncrementalOptions 增量同步的参数
+type IncrementalOptions struct {
+	// TSParam 时间戳参数名，默认 ts
+	TSParam string
+	// FullParam 增量同步时设为 1 的参数名，用于获取上游已删除的数据，默认 full
+	FullParam string
+	// Overlap 从最后的更新时间往前回退的时间窗口，默认 1 秒，小于 0 时不回退。
+	// 数据库中的时间只精确到秒，与最后一条数据同一秒内更新的数据不会被漏掉，重复的数据按主键更新
+	Overlap time.Duration
+}
+
+func (opts *IncrementalOptions) setDefault() {
+	if opts.TSParam == "" {
+		opts.TSParam = "ts"
+	}
+	if opts.FullParam == "" {
+		opts.FullParam = "full"
+	}
+	if opts.Overlap == 0 {
+		opts.Overlap = time.Second
+	}
+	if opts.Overlap < 0 {
+		opts.Overlap = 0
+	}
+}
+
+/*
+syncIncrementalToDB 根据数据库中最后的更新时间自动选择全量或增量同步
+
+  - 表为空（或没有更新时间）时，使用 ts=0 全量同步当前有效的数据
+  - 否则使用 ts=最后更新时间-Overlap、full=1 增量同步，包含上游已删除的数据
+  - 设置了 JobName 时，使用同步状态表中的检查点代替最后更新时间
+
+与 example_db.go 中手动调用 GetLastUpdatedTS、SetParam 的方式相同。
+*/
+func syncIncrementalToDB(r Requester, db *gorm.DB, api APIConfig, dataModel interface{}, opts IncrementalOptions) (SyncResult, error) {
+	opts.setDefault()
+	api.SetDefault()
+	// 先处理表结构，才能读取最后的更新时间，之后同步时不再重复处理
+	db, err := prepareDB(db, api, dataModel)
+	if err != nil {
+		return SyncResult{}, err
+	}
+	// 复制参数，避免修改调用方 APIConfig 中的 map
+	api.params = api.Params()
+	ts, ok := int64(0), false
+	if api.JobName != "" {
+		// 有同步状态时只使用检查点，从未成功过时继续全量同步，以便续传
+		if state, err := GetSyncState(db, api.JobName, api.APIPath); err == nil {
+			ts, ok = state.LastTS, true
+		}
+	}
+	if !ok {
+		ts = lastUpdatedTS(db, api, dataModel)
+	}
+	if ts > 0 {
+		// 增量同步的数据不完整，不能用于删除对账
+		api.Reconcile = nil
+		if ts -= int64(opts.Overlap / time.Second); ts < 1 {
+			ts = 1
+		}
+		api.SetParam(opts.TSParam, strconv.FormatInt(ts, 10))
+		api.SetParam(opts.FullParam, "1")
+	} else {
+		api.SetParam(opts.TSParam, "0")
+		api.DelParam(opts.FullParam)
+	}
+	res, err := syncToTable(r, db, api, dataModel, true)
+	if err == nil && api.JobName == "" {
+		res.Checkpoint = lastUpdatedTS(db, api, dataModel)
+	}
+	return res, err
+}
+
+func syncToFile(r Requester, mode string, fileName string, api APIConfig) (res SyncResult, err error) {
+	api.SetDefault()
+	stats := SyncMetrics{APIPath: api.APIPath, Target: mode}
+	defer observeSync(r, api, &stats, time.Now(), &res, &err)
+	ctx, span := startSyncSpan(r, api, mode)
+	defer endSyncSpan(span, &res, &err)
+
+	rows, err := getAllRows(ctx, r, api.fullPath(), api.PageSize, &res)
 	if err != nil {
-		return 0, err
+		return res, err
 	}
+	start := time.Now()
 	switch mode {
 	case "csv":
 		err = parseRowsToCSV(rows, fileName)
 	case "xlsx":
 		err = parseRowsToXLSX(rows, fileName)
+	case "jsonl":
+		err = parseRowsToJSONL(rows, fileName)
 	default:
-		return 0, errors.New("not support mode: csv or xlsx")
+		return res, errors.New("not support mode: csv, xlsx or jsonl")
 	}
+	res.WriteDuration = time.Since(start)
 	if err != nil {
-		return 0, err
+		res.Rejected = int64(len(rows))
+		return res, err
 	}
-	return int64(len(rows)), nil
+	res.Written = int64(len(rows))
+	return res, nil
 }
 
-func SyncToModel(api APIConfig, dataModel interface{}) error {
-	apiPath := api.APIPath
-	if api.ParamEncode() != "" {
-		if strings.Contains(apiPath, "?") {
-			apiPath = api.APIPath + "&" + api.ParamEncode()
-		} else {
-			apiPath = api.APIPath + "?" + api.ParamEncode()
-		}
-	}
-	c := GetOpenAPIClient()
-	rows, err := c.GetAllRows(apiPath, api.PageSize)
+func syncToModel(r Requester, api APIConfig, dataModel interface{}) (res SyncResult, err error) {
+	api.SetDefault()
+	stats := SyncMetrics{APIPath: api.APIPath, Target: "model"}
+	defer observeSync(r, api, &stats, time.Now(), &res, &err)
+	ctx, span := startSyncSpan(r, api, "model")
+	defer endSyncSpan(span, &res, &err)
+
+	rows, err := getAllRows(ctx, r, api.fullPath(), api.PageSize, &res)
 	if err != nil {
-		return err
+		return res, err
 	}
-	if err := UnmarshalRows(rows, dataModel); err != nil {
-		return err
+	start := time.Now()
+	_, decodeSpan := tracerOf(r).Start(ctx, SpanDecode, Attr(AttrAPIPath, api.APIPath), Attr(AttrRows, len(rows)))
+	err = UnmarshalRows(rows, dataModel)
+	endSpan(decodeSpan, err)
+	res.DecodeDuration = time.Since(start)
+	if err != nil {
+		res.Rejected = int64(len(rows))
+		return res, err
 	}
-	return nil
+	res.Written = int64(len(rows))
+	return res, nil
+}
+
+// observeSync 在同步结束时记录指标
+func observeSync(r Requester, api APIConfig, stats *SyncMetrics, start time.Time, res *SyncResult, err *error) {
+	stats.Pages = res.Pages
+	stats.Rows = res.Rows
+	stats.Deleted = res.Deleted
+	stats.Err = *err
+	stats.Duration = time.Since(start)
+	metricsOf(r, api).ObserveSync(*stats)
+}
+
+// startSyncSpan 为一次同步创建根 span
+func startSyncSpan(r Requester, api APIConfig, target string) (context.Context, Span) {
+	return tracerOf(r).Start(context.Background(), SpanSync,
+		Attr(AttrAPIPath, api.APIPath),
+		Attr(AttrTarget, target),
+		Attr(AttrPageSize, api.PageSize),
+	)
 }
 
-func SyncToDB(db *gorm.DB, api APIConfig, dataModel interface{}) (int64, error) {
+func endSyncSpan(span Span, res *SyncResult, err *error) {
+	span.SetAttributes(Attr(AttrRows, res.Rows))
+	endSpan(span, *err)
+}
+
+func syncToDB(r Requester, db *gorm.DB, api APIConfig, dataModel interface{}) (SyncResult, error) {
+	return syncToTable(r, db, api, dataModel, false)
+}
+
+// prepareDB 返回写入的表，并按 APIConfig.Migrate 处理表结构
+func prepareDB(db *gorm.DB, api APIConfig, dataModel interface{}) (*gorm.DB, error) {
+	db = api.tableDB(db)
+	return db, migrate(db, api, dataModel)
+}
+
+// syncToTable 同步到数据库，prepared 为 true 时 db 已经由 prepareDB 处理过
+func syncToTable(r Requester, db *gorm.DB, api APIConfig, dataModel interface{}, prepared bool) (res SyncResult, err error) {
 	api.SetDefault()
-	if err := db.AutoMigrate(dataModel); err != nil {
-		return 0, err
-	}
-	apiPath := api.APIPath
-	if api.ParamEncode() != "" {
-		if strings.Contains(apiPath, "?") {
-			apiPath = api.APIPath + "&" + api.ParamEncode()
-		} else {
-			apiPath = api.APIPath + "?" + api.ParamEncode()
+	stats := SyncMetrics{APIPath: api.APIPath, Target: "db"}
+	defer observeSync(r, api, &stats, time.Now(), &res, &err)
+	ctx, span := startSyncSpan(r, api, "db")
+	defer endSyncSpan(span, &res, &err)
+	tracer := tracerOf(r)
+
+	if !prepared {
+		if db, err = prepareDB(db, api, dataModel); err != nil {
+			return res, err
 		}
 	}
-	c := GetOpenAPIClient()
+	apiPath := api.fullPath()
 	pageNum := 1
-	rowsCount := int64(0)
+	var keys *keyCollector
+	if api.Reconcile != nil {
+		if keys, err = newKeyCollector(db, dataModel); err != nil {
+			return res, err
+		}
+	}
+	conflict, err := onConflict(db, api, dataModel)
+	if err != nil {
+		return res, err
+	}
+	counter, err := newUpsertCounter(db, api, dataModel, conflict)
+	if err != nil {
+		return res, err
+	}
+	var marker *markerDeleter
+	if api.DeleteMarker != nil {
+		if marker, err = newMarkerDeleter(db, dataModel, *api.DeleteMarker); err != nil {
+			return res, err
+		}
+	}
+	// 设置了 JobName 时记录同步状态，上一次中断时从已写入的页之后继续
+	var state *SyncState
+	resumed := false
+	if api.JobName != "" {
+		if state, err = beginSyncState(db, api, apiPath); err != nil {
+			return res, err
+		}
+		pageNum = state.LastPage + 1
+		resumed = pageNum > 1
+		defer func() {
+			if serr := finishSyncState(db, state, err); serr != nil && err == nil {
+				err = serr
+			}
+			if err == nil {
+				res.Checkpoint = state.LastTS
+			}
+		}()
+	}
 	for {
-		res, err := c.GetRows(apiPath, pageNum, api.PageSize)
+		page, err := fetchPage(ctx, r, apiPath, pageNum, api.PageSize, &res)
 		if err != nil {
-			return rowsCount, err
+			return res, err
+		}
+
+		rows, deletedRows := page.Rows, []interface{}(nil)
+		if marker != nil {
+			rows, deletedRows = api.DeleteMarker.split(page.Rows)
 		}
 
 		//利用反射创建一个结构相同的临时空间，是个指针
 		tmpData, err := newStructSlice(dataModel)
 		if err != nil {
-			return rowsCount, err
+			return res, err
+		}
+		deletedData, err := newStructSlice(dataModel)
+		if err != nil {
+			return res, err
 		}
 
-		if err := UnmarshalRows(res.Rows, tmpData); err != nil {
-			return rowsCount, err
+		start := time.Now()
+		_, decodeSpan := tracer.Start(ctx, SpanDecode,
+			Attr(AttrAPIPath, api.APIPath),
+			Attr(AttrPageNum, pageNum),
+			Attr(AttrRows, len(page.Rows)),
+		)
+		err = UnmarshalRows(rows, tmpData)
+		if err == nil {
+			err = UnmarshalRows(deletedRows, deletedData)
+		}
+		endSpan(decodeSpan, err)
+		res.DecodeDuration += time.Since(start)
+		if err != nil {
+			res.Rejected += int64(len(page.Rows))
+			return res, err
 		}
 
 		//如果空指针后面反射会 panic，容错性处理
@@ -150,27 +385,121 @@ func SyncToDB(db *gorm.DB, api APIConfig, dataModel interface{}) (int64, error)
 		} else {
 			//数据结构已知，如果不是空指针那一定是数组，所以 Len 方法必然有效
 			v := reflect.Indirect(reflect.ValueOf(tmpData))
-			if v.Len() == 0 {
+			if len(page.Rows) == 0 {
 				break
 			}
-			rowsCount = rowsCount + int64(v.Len())
+			stats.Batches += int64((v.Len() + api.BatchSize - 1) / api.BatchSize)
 		}
 
-		sqlResult := db.Clauses(clause.OnConflict{
-			UpdateAll: true,
-		}).CreateInBatches(tmpData, api.BatchSize)
-
-		if sqlResult.Error != nil {
-			return rowsCount, sqlResult.Error
+		start = time.Now()
+		err = writePage(ctx, tracer, db, api, conflict, counter, marker, pageNum, tmpData, deletedData, &res)
+		res.WriteDuration += time.Since(start)
+		if err != nil {
+			return res, err
+		}
+		if keys != nil {
+			keys.add(tmpData)
+		}
+		if state != nil {
+			if err := commitSyncPage(db, state, pageNum, int64(len(page.Rows))); err != nil {
+				return res, err
+			}
 		}
+		res.LastPage = pageNum
 
 		pageNum = pageNum + 1
 	}
-	return rowsCount, nil
+	// 续传时之前的页没有记录主键，无法对账
+	if keys != nil && resumed {
+		res.ReconcileSkipped = true
+	} else if keys != nil {
+		start := time.Now()
+		deleted, err := keys.reconcile(db, dataModel, *api.Reconcile)
+		res.WriteDuration += time.Since(start)
+		if err != nil {
+			return res, err
+		}
+		res.Deleted += deleted
+	}
+	return res, nil
+}
+
+// writePage 写入一页有效的数据，并删除带有删除标记的行，失败时没有保存的行计入 Rejected
+func writePage(ctx context.Context, tracer Tracer, db *gorm.DB, api APIConfig, conflict clause.OnConflict,
+	counter *upsertCounter, marker *markerDeleter, pageNum int, data, deleted interface{}, res *SyncResult) error {
+	before := *res
+	rows := int64(reflect.Indirect(reflect.ValueOf(data)).Len())
+	if err := createInBatches(ctx, tracer, db, api, conflict, counter, pageNum, data, res); err != nil {
+		if !db.SkipDefaultTransaction {
+			// 整页在一个事务中回滚
+			res.Written, res.Inserted, res.Updated, res.Unchanged = before.Written, before.Inserted, before.Updated, before.Unchanged
+		}
+		res.Rejected += rows - (res.Written - before.Written)
+		return err
+	}
+	if marker != nil {
+		count, err := marker.delete(db, deleted)
+		if err != nil {
+			res.Rejected += int64(reflect.Indirect(reflect.ValueOf(deleted)).Len())
+			return err
+		}
+		res.Deleted += count
+	}
+	return nil
 }
 
+// createInBatches 与 gorm 的 CreateInBatches 相同，分批写入一页数据，同时为每一批创建一个 span
+func createInBatches(ctx context.Context, tracer Tracer, db *gorm.DB, api APIConfig, conflict clause.OnConflict,
+	counter *upsertCounter, pageNum int, data interface{}, res *SyncResult) error {
+	if !db.SkipDefaultTransaction {
+		return db.Transaction(func(tx *gorm.DB) error {
+			return createBatches(ctx, tracer, tx, api, conflict, counter, pageNum, data, res)
+		})
+	}
+	return createBatches(ctx, tracer, db, api, conflict, counter, pageNum, data, res)
+}
+
+func createBatches(ctx context.Context, tracer Tracer, db *gorm.DB, api APIConfig, conflict clause.OnConflict,
+	counter *upsertCounter, pageNum int, data interface{}, res *SyncResult) error {
+	v := reflect.Indirect(reflect.ValueOf(data))
+	for i := 0; i < v.Len(); i += api.BatchSize {
+		end := i + api.BatchSize
+		if end > v.Len() {
+			end = v.Len()
+		}
+		_, span := tracer.Start(ctx, SpanBatch,
+			Attr(AttrAPIPath, api.APIPath),
+			Attr(AttrPageNum, pageNum),
+			Attr(AttrRows, end-i),
+		)
+		batch := reflect.New(v.Type())
+		batch.Elem().Set(v.Slice(i, end))
+		counted := *res
+		err := counter.count(db, batch.Interface(), res)
+		if err == nil {
+			err = db.Clauses(conflict).Create(batch.Interface()).Error
+		}
+		if err == nil {
+			res.Written += int64(end - i)
+		} else {
+			res.Inserted, res.Updated, res.Unchanged = counted.Inserted, counted.Updated, counted.Unchanged
+		}
+		endSpan(span, err)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetLastUpdatedTS 返回表中最后的更新时间，设置了 APIConfig.Table 时读取指定的表，没有数据时返回 0
 func GetLastUpdatedTS(db *gorm.DB, api APIConfig, dataModel interface{}) int64 {
 	api.SetDefault()
+	return lastUpdatedTS(api.tableDB(db), api, dataModel)
+}
+
+// lastUpdatedTS 从已经解析过的表中读取最后的更新时间
+func lastUpdatedTS(db *gorm.DB, api APIConfig, dataModel interface{}) int64 {
 	type result struct {
 		TS sql.NullTime `gorm:"column:ts"`
 	}
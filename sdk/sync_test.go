@@ -0,0 +1,269 @@
+package sdk
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk/sdktest"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testAPIPath = "/api/v1/sync/fakewithts"
+
+type testFakeRow struct {
+	Id          int       `json:"id" gorm:"primarykey;autoIncrement:false"`
+	UpdateTime  time.Time `json:"updated_at" time_format:"sql_datetime" time_location:"shanghai" gorm:"index;column:updated_at"`
+	DeletedMark int       `json:"deleted_mark"`
+	Name        string    `json:"name"`
+}
+
+func newTestRows(n int, updatedAt string) []map[string]interface{} {
+	rows := make([]map[string]interface{}, 0, n)
+	for i := 1; i <= n; i++ {
+		rows = append(rows, map[string]interface{}{
+			"id":           i,
+			"updated_at":   updatedAt,
+			"deleted_mark": 0,
+			"name":         fmt.Sprintf("name%d", i),
+		})
+	}
+	return rows
+}
+
+// newTestClient 返回上游数据为 rows 的 MemoryClient
+func newTestClient(rows []map[string]interface{}) *MemoryClient {
+	data := make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		data = append(data, row)
+	}
+	mc := NewMemoryClient()
+	mc.AddRows(testAPIPath, data)
+	return mc
+}
+
+// newTestServer 启动模拟网关，并将全局 client 指向它
+func newTestServer(t *testing.T) *sdktest.Server {
+	t.Helper()
+	srv := sdktest.NewServer()
+	t.Cleanup(srv.Close)
+	err := SetupOAuth2ClientCredentials(OAuth2Config{
+		ClientId:     srv.ClientId,
+		ClientSecret: srv.ClientSecret,
+		BaseUrl:      srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func Test_SyncToDB(t *testing.T) {
+	srv := newTestServer(t)
+	rows := newTestRows(25, "2023-01-02 00:00:00")
+	rows[24]["deleted_mark"] = 1
+	rows[0]["updated_at"] = "2023-01-05 00:00:00"
+	if _, err := srv.AddRows(testAPIPath, rows); err != nil {
+		t.Fatal(err)
+	}
+	db := newTestDB(t)
+
+	api := APIConfig{APIPath: testAPIPath, PageSize: 10}
+	api.SetParam("ts", "0")
+	res, err := SyncToDB(db, api, &[]testFakeRow{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 全量同步不返回已删除的数据
+	if res.Rows != 24 {
+		t.Errorf("full sync should get 24 rows, got %d", res.Rows)
+	}
+
+	ts := GetLastUpdatedTS(db, api, testFakeRow{})
+	if want := time.Date(2023, 1, 5, 0, 0, 0, 0, time.FixedZone("CST", 8*3600)).Unix(); ts != want {
+		t.Errorf("last updated ts should be %d, got %d", want, ts)
+	}
+
+	api.SetParam("ts", fmt.Sprintf("%d", time.Date(2023, 1, 3, 0, 0, 0, 0, time.FixedZone("CST", 8*3600)).Unix()))
+	api.SetParam("full", "1")
+	res, err = SyncToDB(db, api, &[]testFakeRow{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Rows != 1 {
+		t.Errorf("incremental sync should get 1 row, got %d", res.Rows)
+	}
+}
+
+func Test_SyncToDBRetryInvalidToken(t *testing.T) {
+	srv := newTestServer(t)
+	if _, err := srv.AddRows(testAPIPath, newTestRows(5, "2023-01-02 00:00:00")); err != nil {
+		t.Fatal(err)
+	}
+	db := newTestDB(t)
+
+	// 先获取一次 token，再让 token 失效
+	if _, err := GetOpenAPIClient().GetRows(testAPIPath, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	srv.InjectFault(sdktest.InvalidToken(testAPIPath, 1))
+
+	res, err := SyncToDB(db, APIConfig{APIPath: testAPIPath}, &[]testFakeRow{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Rows != 5 {
+		t.Errorf("sync should get 5 rows after token refresh, got %d", res.Rows)
+	}
+	if srv.Requests("/oauth2/token") != 2 {
+		t.Errorf("token should be fetched twice, got %d", srv.Requests("/oauth2/token"))
+	}
+}
+
+func Test_SyncToDBFaults(t *testing.T) {
+	srv := newTestServer(t)
+	if _, err := srv.AddRows(testAPIPath, newTestRows(30, "2023-01-02 00:00:00")); err != nil {
+		t.Fatal(err)
+	}
+	db := newTestDB(t)
+	api := APIConfig{APIPath: testAPIPath, PageSize: 10}
+
+	srv.InjectFault(sdktest.GatewayError(testAPIPath, http.StatusForbidden, "A403IP", "IP not allowed"))
+	_, err := SyncToDB(db, api, &[]testFakeRow{})
+	if err == nil || !strings.Contains(err.Error(), "A403IP") {
+		t.Errorf("gateway error code should be reported, got %v", err)
+	}
+
+	srv.ClearFaults()
+	srv.InjectFault(sdktest.MalformedJSON(testAPIPath, 2))
+	res, err := SyncToDB(db, api, &[]testFakeRow{})
+	if err == nil {
+		t.Error("malformed json should fail")
+	}
+	if res.Rows != 10 {
+		t.Errorf("first page should be synced before failure, got %d", res.Rows)
+	}
+}
+
+func Test_SyncToFileJSONL(t *testing.T) {
+	srv := newTestServer(t)
+	if _, err := srv.AddRows(testAPIPath, newTestRows(15, "2023-01-02 00:00:00")); err != nil {
+		t.Fatal(err)
+	}
+	fileName := filepath.Join(t.TempDir(), "rows.jsonl")
+	res, err := SyncToFile("jsonl", fileName, APIConfig{APIPath: testAPIPath, PageSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if res.Rows != 15 || len(lines) != 15 {
+		t.Fatalf("expected 15 rows, got %d %d", res.Rows, len(lines))
+	}
+	var row testFakeRow
+	if err := jsonTime.Unmarshal([]byte(lines[14]), &row); err != nil {
+		t.Fatal(err)
+	}
+	if row.Id != 15 || row.Name != "name15" || row.UpdateTime.IsZero() {
+		t.Errorf("unexpected row: %+v", row)
+	}
+
+	if _, err := SyncToFile("xml", fileName, APIConfig{APIPath: testAPIPath}); err == nil {
+		t.Error("expected error for unsupported mode")
+	}
+}
+
+func Test_SyncIncrementalToDB(t *testing.T) {
+	mc := newTestClient(newTestRows(3, "2023-01-02 00:00:00"))
+	db := newTestDB(t)
+	api := APIConfig{APIPath: testAPIPath, PageSize: 10}
+	api.SetParam("departmentId", "0445")
+
+	// 表为空时全量同步
+	res, err := mc.SyncIncrementalToDB(db, api, &[]testFakeRow{}, IncrementalOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := mc.Calls()
+	if res.Rows != 3 || !strings.Contains(calls[0], "ts=0") || strings.Contains(calls[0], "full") {
+		t.Errorf("unexpected full sync: %d %v", res.Rows, calls)
+	}
+
+	// 之后从最后的更新时间往前回退 1 秒增量同步
+	loc, _ := time.LoadLocation("Asia/Shanghai")
+	ts := time.Date(2023, 1, 2, 0, 0, 0, 0, loc).Unix()
+	if _, err := mc.SyncIncrementalToDB(db, api, &[]testFakeRow{}, IncrementalOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	calls = mc.Calls()
+	if last := calls[len(calls)-1]; !strings.Contains(last, fmt.Sprintf("full=1&ts=%d", ts-1)) || !strings.Contains(last, "departmentId=0445") {
+		t.Errorf("unexpected incremental sync: %s", last)
+	}
+
+	opts := IncrementalOptions{TSParam: "since", FullParam: "deleted", Overlap: -1}
+	if _, err := mc.SyncIncrementalToDB(db, api, &[]testFakeRow{}, opts); err != nil {
+		t.Fatal(err)
+	}
+	calls = mc.Calls()
+	if last := calls[len(calls)-1]; !strings.Contains(last, fmt.Sprintf("deleted=1&departmentId=0445&since=%d", ts)) {
+		t.Errorf("unexpected incremental sync: %s", last)
+	}
+	if api.ParamEncode() != "departmentId=0445" {
+		t.Errorf("api params should not be changed: %s", api.ParamEncode())
+	}
+}
+
+func Test_SyncIncrementalToDBMigrateOnce(t *testing.T) {
+	db := newTestDB(t)
+	// 记录检查表结构的查询次数
+	migrations := 0
+	count := func(tx *gorm.DB) {
+		if strings.Contains(tx.Statement.SQL.String(), "sqlite_master") {
+			migrations++
+		}
+	}
+	if err := db.Callback().Row().After("gorm:row").Register("test:migrations", count); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Callback().Query().After("gorm:query").Register("test:migrations", count); err != nil {
+		t.Fatal(err)
+	}
+	mc := newTestClient(newTestRows(3, "2023-01-02 00:00:00"))
+	api := APIConfig{APIPath: testAPIPath, Table: "rows"}
+	for i := 0; i < 2; i++ {
+		migrations = 0
+		if _, err := mc.SyncToDB(db, api, &[]testFakeRow{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 表已经存在时一次 SyncToDB 的查询次数
+	once := migrations
+
+	migrations = 0
+	if _, err := mc.SyncIncrementalToDB(db, api, &[]testFakeRow{}, IncrementalOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if once == 0 || migrations != once {
+		t.Errorf("incremental sync should migrate once, got %d queries, expected %d", migrations, once)
+	}
+}
@@ -0,0 +1,128 @@
+package sdk
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 同步状态
+const (
+	SyncStatusRunning = "running"
+	SyncStatusSuccess = "success"
+	SyncStatusFailed  = "failed"
+)
+
+/*
+SyncState 同步状态表，APIConfig.JobName 不为空时由 SDK 在目标数据库中维护
+
+  - 全量同步中断后，下一次使用相同参数和 PageSize 同步时，从 LastPage 的下一页继续
+  - 同步成功后才会将 LastTS 推进到本次同步开始的时间，SyncIncrementalToDB 优先使用它作为 ts
+*/
+type SyncState struct {
+	JobName string `gorm:"primaryKey;size:191"`
+	APIPath string `gorm:"primaryKey;size:191"`
+	// Query 带有参数的接口地址，参数变化时不会续传
+	Query    string `gorm:"size:2048"`
+	PageSize int
+	Status   string `gorm:"size:16"`
+	// LastPage 最后一个已经写入数据库的页码，同步成功后清零
+	LastPage int
+	// LastTS 最后一次同步成功的检查点，为开始同步时的 unix 时间戳
+	LastTS int64
+	// RunTS 本次同步开始的时间，续传时保持不变，成功后写入 LastTS
+	RunTS int64
+	// Rows 本次同步写入的行数，续传时包含之前已经写入的行
+	Rows int64
+	// TotalRows 所有同步累计写入的行数
+	TotalRows  int64
+	StartedAt  time.Time
+	FinishedAt time.Time
+	LastError  string `gorm:"size:1024"`
+}
+
+// TableName 同步状态表名
+func (SyncState) TableName() string {
+	return "ecnu_sync_state"
+}
+
+// GetSyncState 读取同步状态，没有记录时返回 gorm.ErrRecordNotFound
+func GetSyncState(db *gorm.DB, jobName, apiPath string) (SyncState, error) {
+	var state SyncState
+	db = db.Session(&gorm.Session{NewDB: true})
+	if !db.Migrator().HasTable(&SyncState{}) {
+		return state, gorm.ErrRecordNotFound
+	}
+	err := db.Where("job_name = ? AND api_path = ?", jobName, apiPath).Take(&state).Error
+	return state, err
+}
+
+// beginSyncState 开始同步，上一次相同参数的同步中断时返回续传的页码
+func beginSyncState(db *gorm.DB, api APIConfig, query string) (*SyncState, error) {
+	// db 可能通过 Table 指定了数据表，状态表使用新的会话
+	db = db.Session(&gorm.Session{NewDB: true})
+	if err := db.AutoMigrate(&SyncState{}); err != nil {
+		return nil, fmt.Errorf("migrate sync state fail: %v", err)
+	}
+	state, err := GetSyncState(db, api.JobName, api.APIPath)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("get sync state fail: %v", err)
+	}
+	now := time.Now()
+	resume := state.Status != SyncStatusSuccess && state.LastPage > 0 &&
+		state.Query == query && state.PageSize == api.PageSize
+	if !resume {
+		state.LastPage = 0
+		state.RunTS = now.Unix()
+		state.Rows = 0
+	}
+	state.JobName = api.JobName
+	state.APIPath = api.APIPath
+	state.Query = query
+	state.PageSize = api.PageSize
+	state.Status = SyncStatusRunning
+	state.StartedAt = now
+	if err := db.Save(&state).Error; err != nil {
+		return nil, fmt.Errorf("save sync state fail: %v", err)
+	}
+	return &state, nil
+}
+
+// commitSyncPage 一页数据写入后记录页码
+func commitSyncPage(db *gorm.DB, state *SyncState, pageNum int, rows int64) error {
+	state.LastPage = pageNum
+	state.Rows += rows
+	state.TotalRows += rows
+	err := db.Session(&gorm.Session{NewDB: true}).Model(state).Updates(map[string]interface{}{
+		"last_page":  state.LastPage,
+		"rows":       state.Rows,
+		"total_rows": state.TotalRows,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("save sync state fail: %v", err)
+	}
+	return nil
+}
+
+// finishSyncState 同步结束，成功时推进 LastTS 并清除续传的页码
+func finishSyncState(db *gorm.DB, state *SyncState, err error) error {
+	state.FinishedAt = time.Now()
+	if err != nil {
+		state.Status = SyncStatusFailed
+		state.LastError = err.Error()
+		if msg := []rune(state.LastError); len(msg) > 1024 {
+			state.LastError = string(msg[:1024])
+		}
+	} else {
+		state.Status = SyncStatusSuccess
+		state.LastError = ""
+		state.LastTS = state.RunTS
+		state.LastPage = 0
+	}
+	if err := db.Session(&gorm.Session{NewDB: true}).Save(state).Error; err != nil {
+		return fmt.Errorf("save sync state fail: %v", err)
+	}
+	return nil
+}
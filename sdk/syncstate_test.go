@@ -0,0 +1,82 @@
+package sdk
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk/sdktest"
+)
+
+func Test_SyncStateResume(t *testing.T) {
+	srv := newTestServer(t)
+	if _, err := srv.AddRows(testAPIPath, newTestRows(25, "2023-01-02 00:00:00")); err != nil {
+		t.Fatal(err)
+	}
+	srv.InjectFault(sdktest.Fault{Path: testAPIPath, Page: 3, Times: 1, Status: http.StatusInternalServerError, ErrorCode: "X500ER"})
+	db := newTestDB(t)
+	api := APIConfig{APIPath: testAPIPath, PageSize: 10, JobName: "fake"}
+	api.SetParam("ts", "0")
+
+	// 第 3 页失败，前两页已经写入
+	if _, err := SyncToDB(db, api, &[]testFakeRow{}); err == nil {
+		t.Fatal("expected sync error")
+	}
+	state, err := GetSyncState(db, "fake", testAPIPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Status != SyncStatusFailed || state.LastPage != 2 || state.Rows != 20 || state.LastTS != 0 ||
+		!strings.Contains(state.LastError, "X500ER") {
+		t.Errorf("unexpected failed state: %+v", state)
+	}
+
+	// 从第 3 页继续，成功后推进检查点
+	res, err := SyncToDB(db, api, &[]testFakeRow{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state, err = GetSyncState(db, "fake", testAPIPath); err != nil {
+		t.Fatal(err)
+	}
+	if res.Rows != 5 || state.Status != SyncStatusSuccess || state.LastPage != 0 || state.Rows != 25 ||
+		state.TotalRows != 25 || state.LastTS == 0 || state.LastTS != state.RunTS || state.LastError != "" {
+		t.Errorf("unexpected success state: %d %+v", res.Rows, state)
+	}
+	var total int64
+	db.Model(&testFakeRow{}).Count(&total)
+	if total != 25 {
+		t.Errorf("expected 25 rows, got %d", total)
+	}
+
+	// 成功后再次同步从第 1 页开始
+	if res, err = SyncToDB(db, api, &[]testFakeRow{}); err != nil || res.Rows != 25 {
+		t.Errorf("expected full sync from page 1, got %d %v", res.Rows, err)
+	}
+}
+
+func Test_SyncStateIncremental(t *testing.T) {
+	mc := NewMemoryClient()
+	mc.AddRows(testAPIPath, []interface{}{map[string]interface{}{"id": 1, "updated_at": "2023-01-02 00:00:00", "name": "name1"}})
+	db := newTestDB(t)
+	api := APIConfig{APIPath: testAPIPath, JobName: "fake"}
+
+	if _, err := GetSyncState(db, "fake", testAPIPath); err == nil {
+		t.Error("expected record not found")
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := mc.SyncIncrementalToDB(db, api, &[]testFakeRow{}, IncrementalOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	state, err := GetSyncState(db, "fake", testAPIPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 第二次使用检查点而不是表中最后的更新时间
+	calls := mc.Calls()
+	if !strings.Contains(calls[0], "ts=0") || !strings.Contains(calls[len(calls)-1], fmt.Sprintf("full=1&ts=%d", state.LastTS-1)) {
+		t.Errorf("unexpected calls: %v", calls)
+	}
+}
@@ -0,0 +1,68 @@
+package sdk
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	cc "golang.org/x/oauth2/clientcredentials"
+)
+
+/*
+tokenSource 并发安全的 client_credentials token 管理
+
+同一时刻只有一个调用方会去获取 token，其他调用方等待并复用结果。
+网关返回 A401OT 时，只有失效的 token 与当前缓存的 token 相同时才会清空，
+避免多个并发请求同时失败后重复刷新。
+*/
+type tokenSource struct {
+	conf    *cc.Config
+	ctx     context.Context
+	metrics Metrics
+	tracer  Tracer
+
+	mu    sync.Mutex
+	token *oauth2.Token
+}
+
+func newTokenSource(ctx context.Context, conf *cc.Config) *tokenSource {
+	return &tokenSource{conf: conf, ctx: ctx, metrics: nopMetrics{}, tracer: nopTracer{}}
+}
+
+func (ts *tokenSource) Token() (*oauth2.Token, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.token.Valid() {
+		return ts.token, nil
+	}
+	start := time.Now()
+	// oauth2.Transport 获取 token 时不会传入请求的 context，token 的 span 没有父 span
+	ctx, span := ts.tracer.Start(ts.ctx, SpanToken)
+	token, err := ts.conf.Token(ctx)
+	endSpan(span, err)
+	ts.metrics.ObserveTokenRefresh(time.Since(start), err)
+	if err != nil {
+		return nil, err
+	}
+	ts.token = token
+	return token, nil
+}
+
+// invalidate 清空指定的 access_token，下一次调用 Token 时重新获取
+func (ts *tokenSource) invalidate(accessToken string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.token != nil && ts.token.AccessToken == accessToken {
+		ts.token = nil
+	}
+}
+
+// bearerToken 从请求头中取出 access_token
+func bearerToken(header string) string {
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return header[7:]
+	}
+	return ""
+}
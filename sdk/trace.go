@@ -0,0 +1,241 @@
+package sdk
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 各个环节的 span 名称
+const (
+	SpanSync      = "ecnu.sync"
+	SpanPageFetch = "ecnu.page.fetch"
+	SpanDecode    = "ecnu.page.decode"
+	SpanBatch     = "ecnu.db.batch"
+	SpanToken     = "ecnu.token.fetch"
+	SpanHTTP      = "ecnu.http.request"
+)
+
+// span 属性名称
+const (
+	AttrAPIPath    = "ecnu.api_path"
+	AttrTarget     = "ecnu.sync.target"
+	AttrPageNum    = "ecnu.page_num"
+	AttrPageSize   = "ecnu.page_size"
+	AttrRows       = "ecnu.rows"
+	AttrBatchSize  = "ecnu.batch_size"
+	AttrRequestId  = "ecnu.request_id"
+	AttrErrorCode  = "ecnu.error_code"
+	AttrHTTPMethod = "http.method"
+	AttrHTTPStatus = "http.status_code"
+)
+
+/*
+Tracer 链路追踪接口，实现需要并发安全
+
+SDK 会为每次同步、每页数据的获取、数据解析、每批数据写入以及获取 token 创建 span，
+每个 http 请求也会创建 span，并通过 W3C traceparent 请求头传递给网关。
+接入 OpenTelemetry 可以使用 contrib/otel 子模块。
+*/
+type Tracer interface {
+	// Start 以 ctx 中的 span 为父 span 创建新的 span，返回包含新 span 的 ctx
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span 一个操作的追踪记录
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+	// TraceParent 返回 W3C traceparent 格式的字符串，为空时不传递
+	TraceParent() string
+}
+
+// Attribute span 属性
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Attr 创建 span 属性
+func Attr(key string, value interface{}) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+type nopTracer struct{}
+
+func (nopTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttributes(...Attribute) {}
+func (nopSpan) RecordError(error)          {}
+func (nopSpan) End()                       {}
+func (nopSpan) TraceParent() string        { return "" }
+
+// tracerOf 返回 requester 上配置的 Tracer
+func tracerOf(r Requester) Tracer {
+	if c, ok := r.(*OAuth2Client); ok && c.tracer != nil {
+		return c.tracer
+	}
+	return nopTracer{}
+}
+
+// endSpan 记录错误并结束 span
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// tracingTransport 为每个 http 请求创建 span，并传递 traceparent
+type tracingTransport struct {
+	tracer Tracer
+	next   http.RoundTripper
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := t.tracer.Start(req.Context(), SpanHTTP,
+		Attr(AttrHTTPMethod, req.Method),
+		Attr(AttrAPIPath, req.URL.Path),
+	)
+	if traceParent := span.TraceParent(); traceParent != "" {
+		// RoundTripper 不能修改原始请求
+		req = req.Clone(ctx)
+		req.Header.Set("traceparent", traceParent)
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		endSpan(span, err)
+		return resp, err
+	}
+	span.SetAttributes(
+		Attr(AttrHTTPStatus, resp.StatusCode),
+		Attr(AttrRequestId, resp.Header.Get("X-Ca-Request-Id")),
+	)
+	if code := resp.Header.Get("X-Ca-Error-Code"); code != "" {
+		span.SetAttributes(Attr(AttrErrorCode, code))
+	}
+	span.End()
+	return resp, nil
+}
+
+/*
+SpanRecorder 在内存中记录 span 的 Tracer，用于测试和调试
+
+	rec := sdk.NewSpanRecorder()
+	cf.Tracer = rec
+	...
+	for _, span := range rec.Spans() {
+		fmt.Println(span.Name, span.Duration(), span.Attributes)
+	}
+*/
+type SpanRecorder struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+// RecordedSpan SpanRecorder 记录的 span
+type RecordedSpan struct {
+	Name       string
+	TraceID    string
+	SpanID     string
+	ParentID   string
+	Attributes map[string]interface{}
+	Err        error
+	StartTime  time.Time
+	EndTime    time.Time
+
+	recorder *SpanRecorder
+}
+
+type recordedSpanKey struct{}
+
+func NewSpanRecorder() *SpanRecorder {
+	return &SpanRecorder{}
+}
+
+func (r *SpanRecorder) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	span := &RecordedSpan{
+		Name:       name,
+		SpanID:     randomHex(8),
+		Attributes: make(map[string]interface{}),
+		StartTime:  time.Now(),
+		recorder:   r,
+	}
+	if parent, ok := ctx.Value(recordedSpanKey{}).(*RecordedSpan); ok {
+		span.TraceID = parent.TraceID
+		span.ParentID = parent.SpanID
+	} else {
+		span.TraceID = randomHex(16)
+	}
+	span.SetAttributes(attrs...)
+	return context.WithValue(ctx, recordedSpanKey{}, span), span
+}
+
+// Spans 返回已经结束的 span，按结束顺序排列
+func (r *SpanRecorder) Spans() []RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res := make([]RecordedSpan, 0, len(r.spans))
+	for _, span := range r.spans {
+		res = append(res, *span)
+	}
+	return res
+}
+
+// Reset 清空已记录的 span
+func (r *SpanRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = nil
+}
+
+func (s *RecordedSpan) SetAttributes(attrs ...Attribute) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	for _, attr := range attrs {
+		s.Attributes[attr.Key] = attr.Value
+	}
+}
+
+func (s *RecordedSpan) RecordError(err error) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.Err = err
+}
+
+func (s *RecordedSpan) End() {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.EndTime = time.Now()
+	attrs := make(map[string]interface{}, len(s.Attributes))
+	for k, v := range s.Attributes {
+		attrs[k] = v
+	}
+	ended := *s
+	ended.Attributes = attrs
+	s.recorder.spans = append(s.recorder.spans, &ended)
+}
+
+func (s *RecordedSpan) TraceParent() string {
+	return fmt.Sprintf("00-%s-%s-01", s.TraceID, s.SpanID)
+}
+
+// Duration span 的耗时
+func (s RecordedSpan) Duration() time.Duration {
+	return s.EndTime.Sub(s.StartTime)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
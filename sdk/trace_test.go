@@ -0,0 +1,127 @@
+package sdk
+
+import (
+	"testing"
+
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk/sdktest"
+)
+
+func Test_TracingSyncToDB(t *testing.T) {
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	if _, err := srv.AddRows(testAPIPath, newTestRows(25, "2023-01-02 00:00:00")); err != nil {
+		t.Fatal(err)
+	}
+	rec := NewSpanRecorder()
+	err := SetupOAuth2ClientCredentials(OAuth2Config{
+		ClientId:     srv.ClientId,
+		ClientSecret: srv.ClientSecret,
+		BaseUrl:      srv.URL,
+		Tracer:       rec,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.InjectFault(sdktest.InvalidToken(testAPIPath, 1))
+
+	api := APIConfig{APIPath: testAPIPath, PageSize: 10, BatchSize: 4}
+	rows := []testFakeRow{}
+	if _, err := SyncToDB(newTestDB(t), api, &rows); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := make(map[string][]RecordedSpan)
+	byID := make(map[string]RecordedSpan)
+	for _, span := range rec.Spans() {
+		spans[span.Name] = append(spans[span.Name], span)
+		byID[span.SpanID] = span
+	}
+	// 25 行数据共 4 页（最后一页为空），每页 10、10、5 行按 4 行一批写入
+	for name, want := range map[string]int{
+		SpanSync:      1,
+		SpanPageFetch: 4,
+		SpanDecode:    4,
+		SpanBatch:     8,
+		SpanHTTP:      5, // 第一次请求返回 A401OT 后重试
+		SpanToken:     2,
+	} {
+		if len(spans[name]) != want {
+			t.Errorf("expected %d %s spans, got %d", want, name, len(spans[name]))
+		}
+	}
+
+	root := spans[SpanSync][0]
+	if root.ParentID != "" || root.Attributes[AttrRows] != int64(25) || root.Attributes[AttrTarget] != "db" {
+		t.Errorf("unexpected sync span: %+v", root)
+	}
+	for _, name := range []string{SpanPageFetch, SpanDecode, SpanBatch} {
+		for _, span := range spans[name] {
+			if span.ParentID != root.SpanID || span.TraceID != root.TraceID {
+				t.Errorf("%s span should be a child of the sync span", name)
+			}
+		}
+	}
+	if page := spans[SpanPageFetch][0]; page.Attributes[AttrPageNum] != 1 || page.Attributes[AttrRows] != 10 {
+		t.Errorf("unexpected page span attributes: %v", page.Attributes)
+	}
+
+	var lastHTTP RecordedSpan
+	for _, span := range spans[SpanHTTP] {
+		if parent := byID[span.ParentID]; parent.Name != SpanPageFetch {
+			t.Errorf("http span should be a child of a page span, got parent %q", parent.Name)
+		}
+		if span.Attributes[AttrRequestId] == "" {
+			t.Error("http span should record X-Ca-Request-Id")
+		}
+		lastHTTP = span
+	}
+	if code := spans[SpanHTTP][0].Attributes[AttrErrorCode]; code != "A401OT" {
+		t.Errorf("first http span should record A401OT, got %v", code)
+	}
+
+	want := "00-" + lastHTTP.TraceID + "-" + lastHTTP.SpanID + "-01"
+	if got := srv.LastHeader(testAPIPath).Get("traceparent"); got != want {
+		t.Errorf("traceparent should be %q, got %q", want, got)
+	}
+}
+
+func Test_TracingRecordsError(t *testing.T) {
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	if _, err := srv.AddRows(testAPIPath, newTestRows(5, "2023-01-02 00:00:00")); err != nil {
+		t.Fatal(err)
+	}
+	rec := NewSpanRecorder()
+	err := SetupOAuth2ClientCredentials(OAuth2Config{
+		ClientId:     srv.ClientId,
+		ClientSecret: srv.ClientSecret,
+		BaseUrl:      srv.URL,
+		Tracer:       rec,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.InjectFault(sdktest.ServerError(testAPIPath, 1, 500))
+
+	rows := []testFakeRow{}
+	if _, err := SyncToModel(APIConfig{APIPath: testAPIPath}, &rows); err == nil {
+		t.Fatal("sync should fail")
+	}
+	seen := make(map[string]bool)
+	for _, span := range rec.Spans() {
+		seen[span.Name] = true
+		switch span.Name {
+		case SpanPageFetch, SpanSync:
+			if span.Err == nil {
+				t.Errorf("%s span should record the error", span.Name)
+			}
+		case SpanHTTP:
+			if span.Attributes[AttrHTTPStatus] != 500 {
+				t.Errorf("http span should record status 500, got %v", span.Attributes[AttrHTTPStatus])
+			}
+		}
+	}
+	if !seen[SpanSync] || !seen[SpanPageFetch] || !seen[SpanHTTP] {
+		t.Errorf("sync, page and http spans should be recorded, got %v", seen)
+	}
+}
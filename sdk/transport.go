@@ -0,0 +1,175 @@
+package sdk
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+/*
+TransportConfig 获取 token 和调用接口共用的 http 传输配置
+
+HTTPClient 或 Transport 不为空时，直接使用其作为底层传输，代理、证书、连接池等配置不再生效。
+否则在 http.DefaultTransport 的基础上应用这里的配置。
+*/
+type TransportConfig struct {
+	// HTTPClient 自定义的底层 http.Client，只使用它的 Transport 和 Timeout
+	HTTPClient *http.Client `json:"-"`
+	// Transport 自定义的底层 RoundTripper
+	Transport http.RoundTripper `json:"-"`
+
+	// ProxyURL 代理地址，例如 http://proxy.ecnu.edu.cn:3128，默认读取 HTTP_PROXY/HTTPS_PROXY 环境变量
+	ProxyURL string `json:"proxy_url"`
+
+	// RootCAFiles 额外信任的 CA 证书文件（PEM），用于私有 CA 的测试环境
+	RootCAFiles []string `json:"root_ca_files"`
+	// RootCAs 额外信任的 CA 证书，会与 RootCAFiles 合并
+	RootCAs *x509.CertPool `json:"-"`
+	// ClientCertFile、ClientKeyFile 双向 TLS 使用的客户端证书和私钥（PEM）
+	ClientCertFile string `json:"client_cert_file"`
+	ClientKeyFile  string `json:"client_key_file"`
+	// Certificates 双向 TLS 使用的客户端证书，会与 ClientCertFile 合并
+	Certificates []tls.Certificate `json:"-"`
+	// InsecureSkipVerify 跳过证书校验，仅限测试环境使用
+	InsecureSkipVerify bool `json:"insecure_skip_verify"`
+
+	MaxIdleConns        int `json:"max_idle_conns"`
+	MaxIdleConnsPerHost int `json:"max_idle_conns_per_host"`
+	MaxConnsPerHost     int `json:"max_conns_per_host"`
+	// DisableHTTP2 禁用 HTTP/2，只使用 HTTP/1.1
+	DisableHTTP2 bool `json:"disable_http2"`
+
+	// 各阶段的超时时间，为 0 时使用 http.DefaultTransport 的默认值
+	DialTimeout           time.Duration `json:"dial_timeout"`
+	TLSHandshakeTimeout   time.Duration `json:"tls_handshake_timeout"`
+	ResponseHeaderTimeout time.Duration `json:"response_header_timeout"`
+	IdleConnTimeout       time.Duration `json:"idle_conn_timeout"`
+	// RequestTimeout 单个请求的总超时时间，优先于 OAuth2Config.Timeout
+	RequestTimeout time.Duration `json:"request_timeout"`
+}
+
+// requestTimeout 返回单个请求的总超时时间
+func (cf OAuth2Config) requestTimeout() time.Duration {
+	if cf.Transport.RequestTimeout > 0 {
+		return cf.Transport.RequestTimeout
+	}
+	if cf.Timeout > 0 {
+		return time.Second * time.Duration(cf.Timeout)
+	}
+	if cf.Transport.HTTPClient != nil && cf.Transport.HTTPClient.Timeout > 0 {
+		return cf.Transport.HTTPClient.Timeout
+	}
+	return time.Second * DefaultTimeout
+}
+
+// newBaseTransport 构造最底层的 RoundTripper
+func (tc TransportConfig) newBaseTransport() (http.RoundTripper, error) {
+	if tc.HTTPClient != nil {
+		if tc.HTTPClient.Transport != nil {
+			return tc.HTTPClient.Transport, nil
+		}
+		return http.DefaultTransport, nil
+	}
+	if tc.Transport != nil {
+		return tc.Transport, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tc.ProxyURL != "" {
+		proxy, err := url.Parse(tc.ProxyURL)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy url: %s", tc.ProxyURL)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	tlsConfig, err := tc.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	if tc.MaxIdleConns > 0 {
+		transport.MaxIdleConns = tc.MaxIdleConns
+	}
+	if tc.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = tc.MaxIdleConnsPerHost
+	}
+	if tc.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = tc.MaxConnsPerHost
+	}
+	if tc.DisableHTTP2 {
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+
+	if tc.DialTimeout > 0 {
+		dialer := &net.Dialer{Timeout: tc.DialTimeout, KeepAlive: 30 * time.Second}
+		transport.DialContext = dialer.DialContext
+	}
+	if tc.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = tc.TLSHandshakeTimeout
+	}
+	if tc.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = tc.ResponseHeaderTimeout
+	}
+	if tc.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = tc.IdleConnTimeout
+	}
+	return transport, nil
+}
+
+// tlsConfig 没有任何 TLS 相关配置时返回 nil，沿用默认配置
+func (tc TransportConfig) tlsConfig() (*tls.Config, error) {
+	if len(tc.RootCAFiles) == 0 && tc.RootCAs == nil && tc.ClientCertFile == "" &&
+		len(tc.Certificates) == 0 && !tc.InsecureSkipVerify {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: tc.InsecureSkipVerify,
+		Certificates:       append([]tls.Certificate(nil), tc.Certificates...),
+	}
+
+	if len(tc.RootCAFiles) > 0 || tc.RootCAs != nil {
+		pool := tc.RootCAs
+		if pool == nil {
+			var err error
+			if pool, err = x509.SystemCertPool(); err != nil {
+				pool = x509.NewCertPool()
+			}
+		} else {
+			pool = pool.Clone()
+		}
+		for _, file := range tc.RootCAFiles {
+			pem, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("read root ca file fail: %v", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificate found in root ca file %s", file)
+			}
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if tc.ClientCertFile != "" || tc.ClientKeyFile != "" {
+		if tc.ClientCertFile == "" || tc.ClientKeyFile == "" {
+			return nil, errors.New("client_cert_file and client_key_file must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(tc.ClientCertFile, tc.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate fail: %v", err)
+		}
+		tlsConfig.Certificates = append(tlsConfig.Certificates, cert)
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,99 @@
+package sdk
+
+import (
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ecnu/ecnu-openapi-sdk-go/sdk/sdktest"
+)
+
+func Test_TransportRootCA(t *testing.T) {
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	srv.AddData(testOrgPath, []string{"0445"})
+	tlsSrv := httptest.NewTLSServer(srv.Config.Handler)
+	defer tlsSrv.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsSrv.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cf := OAuth2Config{ClientId: srv.ClientId, ClientSecret: srv.ClientSecret, BaseUrl: tlsSrv.URL}
+	if err := SetupOAuth2ClientCredentials(cf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetOpenAPIClient().HttpGet(tlsSrv.URL + testOrgPath); err == nil {
+		t.Error("untrusted certificate should fail")
+	}
+
+	cf.Transport.RootCAFiles = []string{caFile}
+	if err := SetupOAuth2ClientCredentials(cf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetOpenAPIClient().HttpGet(tlsSrv.URL + testOrgPath); err != nil {
+		t.Error(err)
+	}
+}
+
+func Test_TransportProxy(t *testing.T) {
+	srv := sdktest.NewServer()
+	defer srv.Close()
+	srv.AddData(testOrgPath, []string{"0445"})
+
+	var proxyHits int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&proxyHits, 1)
+		r.RequestURI = ""
+		resp, err := http.DefaultTransport.RoundTrip(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		for k, v := range resp.Header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+	}))
+	defer proxy.Close()
+
+	err := SetupOAuth2ClientCredentials(OAuth2Config{
+		ClientId:     srv.ClientId,
+		ClientSecret: srv.ClientSecret,
+		BaseUrl:      srv.URL,
+		Transport: TransportConfig{
+			ProxyURL:       proxy.URL,
+			DisableHTTP2:   true,
+			RequestTimeout: 5 * time.Second,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := GetOpenAPIClient()
+	if c.Client.Timeout != 5*time.Second {
+		t.Errorf("request timeout should be applied, got %v", c.Client.Timeout)
+	}
+	if _, err := c.HttpGet(srv.URL + testOrgPath); err != nil {
+		t.Fatal(err)
+	}
+	// token 失效后重建 client，仍然需要经过代理
+	srv.InjectFault(sdktest.InvalidToken(testOrgPath, 1))
+	if _, err := c.HttpGet(srv.URL + testOrgPath); err != nil {
+		t.Fatal(err)
+	}
+	total := srv.Requests("/oauth2/token") + srv.Requests(testOrgPath)
+	if int(proxyHits) != total || total != 5 {
+		t.Errorf("all requests should go through proxy, proxy=%d gateway=%d", proxyHits, total)
+	}
+}
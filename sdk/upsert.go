@@ -0,0 +1,157 @@
+package sdk
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+/*
+UpsertOptions 写入时主键或唯一索引冲突的处理方式，APIConfig.Upsert 为 nil 时更新全部列
+
+  - ConflictColumns 判断冲突的列，默认为主键，使用其他唯一索引时需要设置，SQL Server 的 MERGE 只支持主键
+  - UpdateColumns 冲突时只更新这些列，ExcludeColumns 冲突时不更新这些列，例如只在本地维护的列
+  - DoNothing 为 true 时只插入新数据，已有的数据保持不变
+  - OnlyNewer 为 true 时只有接口数据的 UpdatedAtField 比表中更新时才更新，
+    sqlite 和 PostgreSQL 使用 ON CONFLICT ... WHERE，MySQL 和 SQL Server 使用 CASE 表达式
+*/
+type UpsertOptions struct {
+	ConflictColumns []string `json:"conflict_columns"`
+	UpdateColumns   []string `json:"update_columns"`
+	ExcludeColumns  []string `json:"exclude_columns"`
+	DoNothing       bool     `json:"do_nothing"`
+	OnlyNewer       bool     `json:"only_newer"`
+}
+
+// onConflict 根据 APIConfig.Upsert 生成写入时使用的 ON CONFLICT 子句
+func onConflict(db *gorm.DB, api APIConfig, dataModel interface{}) (clause.OnConflict, error) {
+	opts := api.Upsert
+	if opts == nil {
+		return clause.OnConflict{UpdateAll: true}, nil
+	}
+	dialect := db.Dialector.Name()
+	if len(opts.ConflictColumns) > 0 && dialect == "sqlserver" {
+		return clause.OnConflict{}, fmt.Errorf("upsert fail: conflict columns not support dialect: %s", dialect)
+	}
+	s, err := parseModel(db, dataModel)
+	if err != nil {
+		return clause.OnConflict{}, err
+	}
+
+	conflict := clause.OnConflict{}
+	for _, name := range opts.ConflictColumns {
+		f := s.LookUpField(name)
+		if f == nil {
+			return conflict, fmt.Errorf("upsert fail: unknown conflict column %s", name)
+		}
+		conflict.Columns = append(conflict.Columns, clause.Column{Name: f.DBName})
+	}
+	if opts.DoNothing {
+		conflict.DoNothing = true
+		return conflict, nil
+	}
+	if len(opts.UpdateColumns) == 0 && len(opts.ExcludeColumns) == 0 && !opts.OnlyNewer {
+		conflict.UpdateAll = true
+		return conflict, nil
+	}
+	if len(conflict.Columns) == 0 {
+		for _, f := range s.PrimaryFields {
+			conflict.Columns = append(conflict.Columns, clause.Column{Name: f.DBName})
+		}
+	}
+
+	columns, err := updateColumns(s, opts, conflict.Columns)
+	if err != nil {
+		return conflict, err
+	}
+	if len(columns) == 0 {
+		conflict.DoNothing = true
+		return conflict, nil
+	}
+	if !opts.OnlyNewer {
+		conflict.DoUpdates = clause.AssignmentColumns(columns)
+		return conflict, nil
+	}
+
+	f := s.LookUpField(api.UpdatedAtField)
+	if f == nil {
+		return conflict, fmt.Errorf("upsert fail: unknown updated at column %s", api.UpdatedAtField)
+	}
+	excluded := func(name string) interface{} {
+		if dialect == "mysql" {
+			return clause.Expr{SQL: "VALUES(?)", Vars: []interface{}{clause.Column{Name: name}}}
+		}
+		return clause.Column{Table: "excluded", Name: name}
+	}
+	current := func(name string) clause.Column {
+		return clause.Column{Table: clause.CurrentTable, Name: name}
+	}
+	newer := clause.Expr{
+		SQL:  "(? IS NULL OR ? > ?)",
+		Vars: []interface{}{current(f.DBName), excluded(f.DBName), current(f.DBName)},
+	}
+	switch dialect {
+	case "mysql", "sqlserver":
+		// MySQL 按顺序赋值，后面的表达式读到的是已经更新的值，时间戳列需要最后更新
+		last := false
+		for _, name := range columns {
+			if name == f.DBName {
+				last = true
+				continue
+			}
+			conflict.DoUpdates = append(conflict.DoUpdates, caseAssignment(newer, name, excluded(name), current(name)))
+		}
+		if last {
+			conflict.DoUpdates = append(conflict.DoUpdates, caseAssignment(newer, f.DBName, excluded(f.DBName), current(f.DBName)))
+		}
+	default:
+		conflict.DoUpdates = clause.AssignmentColumns(columns)
+		conflict.Where = clause.Where{Exprs: []clause.Expression{newer}}
+	}
+	return conflict, nil
+}
+
+func caseAssignment(cond clause.Expr, name string, value interface{}, current clause.Column) clause.Assignment {
+	return clause.Assignment{
+		Column: clause.Column{Name: name},
+		Value:  clause.Expr{SQL: "CASE WHEN ? THEN ? ELSE ? END", Vars: []interface{}{cond, value, current}},
+	}
+}
+
+// updateColumns 冲突时更新的列，默认为主键和冲突列以外可以写入的列
+func updateColumns(s *schema.Schema, opts *UpsertOptions, conflict []clause.Column) ([]string, error) {
+	skip := make(map[string]bool)
+	for _, c := range conflict {
+		skip[c.Name] = true
+	}
+	for _, name := range opts.ExcludeColumns {
+		f := s.LookUpField(name)
+		if f == nil {
+			return nil, fmt.Errorf("upsert fail: unknown exclude column %s", name)
+		}
+		skip[f.DBName] = true
+	}
+
+	var columns []string
+	if len(opts.UpdateColumns) > 0 {
+		for _, name := range opts.UpdateColumns {
+			f := s.LookUpField(name)
+			if f == nil {
+				return nil, fmt.Errorf("upsert fail: unknown update column %s", name)
+			}
+			if !skip[f.DBName] {
+				columns = append(columns, f.DBName)
+			}
+		}
+		return columns, nil
+	}
+	for _, name := range s.DBNames {
+		f := s.FieldsByDBName[name]
+		if f.Creatable && !f.PrimaryKey && f.AutoCreateTime == 0 && !skip[name] {
+			columns = append(columns, name)
+		}
+	}
+	return columns, nil
+}